@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// anyTask picks a healthy task of --service running the --container instead of showing the task menu
+var anyTask bool
+
+// pickAnyTask returns the first task of the service that can be exec'd into and runs the named
+// container. Healthy tasks win over tasks without a health check; unhealthy ones are skipped.
+// It returns an empty ARN when no task qualifies.
+func pickAnyTask(client *ecs.Client, clusterArn string, serviceName string, containerName string) (string, error) {
+	tasks, err := listServiceTasks(client, clusterArn, serviceName, nil)
+	if err != nil {
+		return "", err
+	}
+
+	picked := ""
+	for _, task := range tasks {
+		if !task.EnableExecuteCommand || aws.ToString(task.LastStatus) != "RUNNING" || task.HealthStatus == types.HealthStatusUnhealthy {
+			continue
+		}
+		if !runsHealthyContainer(task, containerName) {
+			continue
+		}
+		if task.HealthStatus == types.HealthStatusHealthy {
+			return aws.ToString(task.TaskArn), nil
+		}
+		if picked == "" {
+			picked = aws.ToString(task.TaskArn)
+		}
+	}
+	return picked, nil
+}
+
+// runsHealthyContainer reports whether the task has a running, not unhealthy container of exactly
+// that name. A prefix would also match unrelated containers, e.g. app-sidecar for app.
+func runsHealthyContainer(task types.Task, containerName string) bool {
+	for _, container := range task.Containers {
+		if aws.ToString(container.Name) != containerName {
+			continue
+		}
+		if aws.ToString(container.LastStatus) == "RUNNING" && container.HealthStatus != types.HealthStatusUnhealthy {
+			return true
+		}
+	}
+	return false
+}
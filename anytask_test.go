@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestRunsHealthyContainer(t *testing.T) {
+	task := types.Task{Containers: []types.Container{
+		{Name: aws.String("app-sidecar"), LastStatus: aws.String("RUNNING")},
+		{Name: aws.String("web"), LastStatus: aws.String("RUNNING"), HealthStatus: types.HealthStatusUnhealthy},
+		{Name: aws.String("worker"), LastStatus: aws.String("STOPPED")},
+		{Name: aws.String("api"), LastStatus: aws.String("RUNNING"), HealthStatus: types.HealthStatusHealthy},
+	}}
+
+	tests := []struct {
+		container string
+		want      bool
+	}{
+		{container: "api", want: true},
+		{container: "app-sidecar", want: true},
+		{container: "app", want: false},
+		{container: "ap", want: false},
+		{container: "web", want: false},
+		{container: "worker", want: false},
+		{container: "missing", want: false},
+	}
+	for _, tt := range tests {
+		if got := runsHealthyContainer(task, tt.container); got != tt.want {
+			t.Errorf("runsHealthyContainer(%q) = %t, want %t", tt.container, got, tt.want)
+		}
+	}
+}
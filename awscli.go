@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Oldest AWS CLI releases that support `aws ecs execute-command`
+var (
+	minAWSCLIv1 = [3]int{1, 19, 28}
+	minAWSCLIv2 = [3]int{2, 1, 30}
+)
+
+// awsCLIVersion runs `aws --version` and returns the parsed version, e.g. 2.13.0
+func awsCLIVersion() ([3]int, error) {
+	output, err := exec.Command("aws", "--version").CombinedOutput()
+	if err != nil {
+		return [3]int{}, fmt.Errorf("could not run aws --version: %v", err)
+	}
+	return parseAWSCLIVersion(string(output))
+}
+
+// parseAWSCLIVersion extracts the version from output like "aws-cli/2.13.0 Python/3.11.4 Linux/6.1 ..."
+func parseAWSCLIVersion(output string) ([3]int, error) {
+	var version [3]int
+	for _, field := range strings.Fields(output) {
+		raw, ok := strings.CutPrefix(field, "aws-cli/")
+		if !ok {
+			continue
+		}
+		parts := strings.SplitN(raw, ".", 3)
+		if len(parts) != 3 {
+			break
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return version, fmt.Errorf("unrecognised aws CLI version %q", raw)
+			}
+			version[i] = n
+		}
+		return version, nil
+	}
+	return version, fmt.Errorf("unrecognised aws --version output: %q", strings.TrimSpace(output))
+}
+
+// checkAWSCLIVersion returns an error when the installed AWS CLI is too old for execute-command
+func checkAWSCLIVersion() error {
+	version, err := awsCLIVersion()
+	if err != nil {
+		return err
+	}
+
+	minimum := minAWSCLIv2
+	if version[0] < 2 {
+		minimum = minAWSCLIv1
+	}
+	if versionLess(version, minimum) {
+		return fmt.Errorf("aws CLI %s is too old for execute-command (need %s or newer), please upgrade your aws CLI: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+			formatVersion(version), formatVersion(minimum))
+	}
+	return nil
+}
+
+func versionLess(a [3]int, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func formatVersion(version [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
+
+// ensureSessionBackend checks that sessions have something to start them before any menu is shown.
+// Without the aws CLI the native backend can still connect, as long as session-manager-plugin
+// is installed, so it switches to --native instead of failing at the first session.
+func ensureSessionBackend() {
+	if nativeSession || dryRun {
+		return
+	}
+	if _, err := exec.LookPath("aws"); err == nil {
+		return
+	}
+
+	const installHint = "install it from https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"
+	if _, err := exec.LookPath("session-manager-plugin"); err != nil {
+		fatal(exitConfigError, "%s The aws CLI is not in PATH, %s (or install session-manager-plugin and use --native)", errorIcon(), installHint)
+	}
+	if viewLogs {
+		fatal(exitConfigError, "%s --logs needs the aws CLI, which is not in PATH: %s", errorIcon(), installHint)
+	}
+	log.Printf("%s The aws CLI is not in PATH, starting sessions with --native (the SDK and session-manager-plugin) instead", warnIcon())
+	nativeSession = true
+}
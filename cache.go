@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// listCacheTTL is how long list results are reused when navigating back to a menu
+const listCacheTTL = 30 * time.Second
+
+type listCacheEntry struct {
+	items     any
+	fetchedAt time.Time
+}
+
+var listCache = map[string]listCacheEntry{}
+
+// listCacheKey builds a cache key from the region, cluster and service a list belongs to
+func listCacheKey(parts ...string) string {
+	return strings.Join(parts, "|")
+}
+
+// cachedList returns the cached items for key if they are still fresh, otherwise it calls fetch
+// and caches the result. Passing refresh forces a fetch.
+func cachedList[T any](key string, refresh bool, fetch func() ([]T, error)) ([]T, error) {
+	if entry, ok := listCache[key]; ok && !refresh && time.Since(entry.fetchedAt) < listCacheTTL {
+		if items, ok := entry.items.([]T); ok {
+			return items, nil
+		}
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	listCache[key] = listCacheEntry{items: items, fetchedAt: time.Now()}
+	return items, nil
+}
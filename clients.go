@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// regionClients are the SDK config and clients of one region
+type regionClients struct {
+	Config aws.Config
+	ECS    *ecs.Client
+	EC2    *ec2.Client
+}
+
+// clientCache keeps the clients of every region used in this run, so switching back to a region
+// doesn't load the SDK config again
+var clientCache = map[string]*regionClients{}
+
+// clientsFor returns the clients of the region, loading its SDK config on first use
+func clientsFor(region string) (*regionClients, error) {
+	if clients, ok := clientCache[region]; ok {
+		return clients, nil
+	}
+	cfg, err := loadAWSConfig(region)
+	if err != nil {
+		return nil, err
+	}
+	clients := &regionClients{
+		Config: cfg,
+		ECS:    ecs.NewFromConfig(cfg),
+		EC2:    ec2.NewFromConfig(cfg),
+	}
+	clientCache[region] = clients
+	return clients, nil
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// appConfig is the persistent configuration stored in config.yaml
+type appConfig struct {
+	Region  string `yaml:"region,omitempty"`
+	Profile string `yaml:"profile,omitempty"`
+	// PageSize is the number of items per menu page, 0 shows everything. Unset means the default.
+	PageSize *int `yaml:"page_size,omitempty"`
+
+	// ProtectedTag is the key=value tag that makes a cluster or service ask for confirmation, "none" turns it off
+	ProtectedTag string `yaml:"protected_tag,omitempty"`
+
+	// DefaultCommand is run in every container without showing the command menu
+	DefaultCommand string `yaml:"default_command,omitempty"`
+
+	// DeniedCommands are regular expressions for commands ecs-session refuses to run. It's a guard
+	// against mistakes, not a security boundary: anyone can still run the aws CLI directly.
+	DeniedCommands []string `yaml:"denied_commands,omitempty"`
+
+	// ChooseProfile shows the profile menu whenever no profile is set by flag, environment or config
+	ChooseProfile bool `yaml:"choose_profile,omitempty"`
+
+	// FavoriteRegions are the quick picks of the region menu
+	FavoriteRegions []string `yaml:"favorite_regions,omitempty"`
+
+	// PinnedClusters are shown first in the cluster menu, unless --cluster-order is given
+	PinnedClusters []string `yaml:"pinned_clusters,omitempty"`
+
+	// Theme is the built-in theme, ThemeOverrides replaces single markers or the highlight colour of it
+	Theme          string `yaml:"theme,omitempty"`
+	ThemeOverrides theme  `yaml:"theme_overrides,omitempty"`
+
+	// ClusterCommands maps cluster names to the command preselected in the command menu
+	ClusterCommands map[string]string `yaml:"cluster_commands,omitempty"`
+
+	// ContainerCommands maps container name patterns like db* to the command preselected for
+	// matching containers. It beats ClusterCommands, since it's more specific.
+	ContainerCommands map[string]string `yaml:"container_commands,omitempty"`
+}
+
+// fileConfig is the config file, loaded once for the run before any command starts
+var fileConfig = &appConfig{}
+
+// deniedCommands holds the compiled denied_commands patterns from the config file
+var deniedCommands []*regexp.Regexp
+
+// configKeys lists the settings `config get` and `config set` know about
+var configKeys = []string{"region", "profile", "page-size", "default-command", "protected-tag", "favorite-regions", "pinned-clusters", "choose-profile", "theme"}
+
+// clusterCommandPrefix prefixes the per-cluster command keys, e.g. cluster-command.prod-db
+const clusterCommandPrefix = "cluster-command."
+
+// containerCommandPrefix prefixes the per-container command keys, e.g. container-command.db*
+const containerCommandPrefix = "container-command."
+
+// configPath returns the location of the config file inside the user config directory
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ecs-session", "config.yaml"), nil
+}
+
+// loadConfig reads the config file. A missing file is not an error and yields empty settings.
+func loadConfig() (*appConfig, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &appConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n  %v", path, err)
+	}
+	return cfg, nil
+}
+
+// decodeConfig parses the config file strictly: unknown settings, values of the wrong type and
+// invalid values are all reported with the line they are on
+func decodeConfig(data []byte) (*appConfig, error) {
+	cfg := &appConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(cfg)
+	if errors.Is(err, io.EOF) {
+		// An empty file has no settings
+		return cfg, nil
+	}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages := make([]string, len(typeErr.Errors))
+		for i, message := range typeErr.Errors {
+			message = strings.ReplaceAll(message, " in type main.appConfig", "")
+			if before, field, ok := strings.Cut(message, "field "); ok && strings.HasSuffix(field, " not found") {
+				message = fmt.Sprintf("%sunknown setting %q (known settings: %s)", before, strings.TrimSuffix(field, " not found"), strings.Join(configFileKeys(), ", "))
+			}
+			messages[i] = message
+		}
+		return nil, errors.New(strings.Join(messages, "\n  "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return cfg, validateConfig(cfg, &root)
+}
+
+// configFileKeys returns the settings the config file accepts, as written in the file
+func configFileKeys() []string {
+	var keys []string
+	configType := reflect.TypeOf(appConfig{})
+	for i := 0; i < configType.NumField(); i++ {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("yaml"), ",")
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// validateConfig checks the values that parse but make no sense, naming the line of the setting
+func validateConfig(cfg *appConfig, root *yaml.Node) error {
+	var problems []string
+	report := func(key string, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("line %d: %s", configKeyLine(root, key), fmt.Sprintf(format, args...)))
+	}
+
+	if cfg.PageSize != nil && *cfg.PageSize < 0 {
+		report("page_size", "page_size must not be negative, got %d", *cfg.PageSize)
+	}
+	if cfg.ProtectedTag != "" && cfg.ProtectedTag != "none" && !strings.Contains(cfg.ProtectedTag, "=") {
+		report("protected_tag", "protected_tag must be key=value or none, got %q", cfg.ProtectedTag)
+	}
+	for _, pattern := range cfg.DeniedCommands {
+		if _, err := regexp.Compile(pattern); err != nil {
+			report("denied_commands", "invalid denied_commands pattern %q: %v", pattern, err)
+		}
+	}
+	for _, code := range cfg.FavoriteRegions {
+		if strings.TrimSpace(code) == "" {
+			report("favorite_regions", "favorite_regions has an empty entry")
+		}
+	}
+	for pattern := range cfg.ContainerCommands {
+		if _, err := path.Match(pattern, ""); err != nil {
+			report("container_commands", "invalid container_commands pattern %q: %v", pattern, err)
+		}
+	}
+	if cfg.Theme != "" && !validThemeName(cfg.Theme) {
+		report("theme", "theme must be one of %s, got %q", strings.Join(themeNames(), ", "), cfg.Theme)
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// configKeyLine returns the line of a top-level setting in the parsed config file
+func configKeyLine(root *yaml.Node, key string) int {
+	if len(root.Content) == 0 {
+		return 0
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i].Line
+		}
+	}
+	return mapping.Line
+}
+
+// saveConfig writes the config file atomically, creating its directory if needed
+func saveConfig(cfg *appConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// writeFileAtomic writes the data to a temporary file next to path and renames it into place,
+// so an interrupted write leaves the old file intact instead of a truncated one
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// migrateLegacyRegionFile moves a default region saved by older versions in default_region.txt
+// into the config file, unless the config file already has one
+func migrateLegacyRegionFile(cfg *appConfig) {
+	data, err := os.ReadFile(defaultRegionFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("%s Could not read default region file: %v", warnIcon(), err)
+		}
+		return
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = strings.TrimSpace(string(data))
+		if err := saveConfig(cfg); err != nil {
+			log.Printf("%s Could not move %s into the config file: %v", warnIcon(), defaultRegionFile, err)
+			return
+		}
+	}
+	if err := os.Remove(defaultRegionFile); err != nil {
+		log.Printf("%s Could not remove %s: %v", warnIcon(), defaultRegionFile, err)
+		return
+	}
+	path, _ := configPath()
+	log.Printf("%s Moved the default region from %s into %s", infoIcon(), defaultRegionFile, path)
+}
+
+// applyConfigDefaults fills in flags the user didn't pass from the config file. The region is
+// not applied here because a saved region is only used after asking.
+func applyConfigDefaults(cmd *cobra.Command, cfg *appConfig) {
+	flags := cmd.Flags()
+	// $AWS_PROFILE is an explicit choice for this shell, so it beats the saved profile
+	if !flags.Changed("profile") && os.Getenv("AWS_PROFILE") == "" && cfg.Profile != "" {
+		profile = cfg.Profile
+	}
+	if !flags.Changed("page-size") && cfg.PageSize != nil {
+		pageSize = *cfg.PageSize
+	}
+
+	chooseProfileAlways = cfg.ChooseProfile
+
+	if !flags.Changed("theme") && cfg.Theme != "" {
+		themeName = cfg.Theme
+	}
+
+	if !flags.Changed("cluster-order") {
+		pinnedClusters = cfg.PinnedClusters
+	}
+
+	favoriteRegions = defaultFavoriteRegions
+	if len(cfg.FavoriteRegions) > 0 {
+		favoriteRegions = cfg.FavoriteRegions
+	}
+
+	deniedCommands = nil
+	for _, pattern := range cfg.DeniedCommands {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			fatal(exitConfigError, "%s Invalid denied_commands pattern %q in the config file: %v", errorIcon(), pattern, err)
+		}
+		deniedCommands = append(deniedCommands, compiled)
+	}
+}
+
+// checkDeniedCommand returns an error when the command matches one of the denied_commands patterns
+func checkDeniedCommand(command string) error {
+	for _, pattern := range deniedCommands {
+		if pattern.MatchString(command) {
+			return fmt.Errorf("command %q is blocked by the denied_commands pattern %q in the config file", command, pattern)
+		}
+	}
+	return nil
+}
+
+// preferredCommand returns the command to preselect for the container: the one of the most
+// specific (longest) matching container_commands pattern, else the cluster's cluster_commands entry
+func preferredCommand(cfg *appConfig, clusterName string, containerName string) string {
+	best := ""
+	for pattern := range cfg.ContainerCommands {
+		matched, err := path.Match(pattern, containerName)
+		if err != nil || !matched {
+			continue
+		}
+		if len(pattern) > len(best) || len(pattern) == len(best) && pattern < best {
+			best = pattern
+		}
+	}
+	if best != "" {
+		return cfg.ContainerCommands[best]
+	}
+	return cfg.ClusterCommands[clusterName]
+}
+
+// getConfigValue returns a setting formatted for display
+func getConfigValue(cfg *appConfig, key string) (string, error) {
+	if cluster, ok := strings.CutPrefix(key, clusterCommandPrefix); ok {
+		return cfg.ClusterCommands[cluster], nil
+	}
+	if pattern, ok := strings.CutPrefix(key, containerCommandPrefix); ok {
+		return cfg.ContainerCommands[pattern], nil
+	}
+
+	switch key {
+	case "region":
+		return cfg.Region, nil
+	case "profile":
+		return cfg.Profile, nil
+	case "page-size", "page_size":
+		if cfg.PageSize == nil {
+			return "", nil
+		}
+		return strconv.Itoa(*cfg.PageSize), nil
+	case "default-command", "default_command":
+		return cfg.DefaultCommand, nil
+	case "protected-tag", "protected_tag":
+		return cfg.ProtectedTag, nil
+	case "favorite-regions", "favorite_regions":
+		return strings.Join(cfg.FavoriteRegions, ","), nil
+	case "pinned-clusters", "pinned_clusters":
+		return strings.Join(cfg.PinnedClusters, ","), nil
+	case "choose-profile", "choose_profile":
+		return strconv.FormatBool(cfg.ChooseProfile), nil
+	case "theme":
+		return cfg.Theme, nil
+	default:
+		return "", fmt.Errorf("unknown config key %q (known keys: %v, %s<cluster>, %s<pattern>)", key, configKeys, clusterCommandPrefix, containerCommandPrefix)
+	}
+}
+
+// setConfigValue parses and stores a setting
+func setConfigValue(cfg *appConfig, key string, value string) error {
+	if cluster, ok := strings.CutPrefix(key, clusterCommandPrefix); ok && cluster != "" {
+		if cfg.ClusterCommands == nil {
+			cfg.ClusterCommands = make(map[string]string)
+		}
+		cfg.ClusterCommands[cluster] = value
+		return nil
+	}
+	if pattern, ok := strings.CutPrefix(key, containerCommandPrefix); ok && pattern != "" {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid container name pattern %q: %v", pattern, err)
+		}
+		if cfg.ContainerCommands == nil {
+			cfg.ContainerCommands = make(map[string]string)
+		}
+		cfg.ContainerCommands[pattern] = value
+		return nil
+	}
+
+	switch key {
+	case "region":
+		cfg.Region = value
+	case "profile":
+		cfg.Profile = value
+	case "page-size", "page_size":
+		// An empty value goes back to the default page size
+		if value == "" {
+			cfg.PageSize = nil
+			return nil
+		}
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return fmt.Errorf("page-size must be a non-negative number, got %q", value)
+		}
+		cfg.PageSize = &size
+	case "default-command", "default_command":
+		cfg.DefaultCommand = value
+	case "protected-tag", "protected_tag":
+		if value != "none" && !strings.Contains(value, "=") {
+			return fmt.Errorf("protected-tag must be key=value or none, got %q", value)
+		}
+		cfg.ProtectedTag = value
+	case "favorite-regions", "favorite_regions":
+		// An empty value goes back to the default quick picks
+		cfg.FavoriteRegions = splitList(value)
+	case "pinned-clusters", "pinned_clusters":
+		cfg.PinnedClusters = splitList(value)
+	case "choose-profile", "choose_profile":
+		choose, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("choose-profile must be true or false, got %q", value)
+		}
+		cfg.ChooseProfile = choose
+	case "theme":
+		if value != "" && !validThemeName(value) {
+			return fmt.Errorf("theme must be one of %s, got %q", strings.Join(themeNames(), ", "), value)
+		}
+		cfg.Theme = value
+	default:
+		return fmt.Errorf("unknown config key %q (known keys: %v, %s<cluster>, %s<pattern>)", key, configKeys, clusterCommandPrefix, containerCommandPrefix)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping blanks around and between the items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// newConfigCommand builds the `config` subcommand tree for managing persistent settings
+func newConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "⚙️  View and change saved settings",
+	}
+
+	getCmd := &cobra.Command{
+		Use:   "get [key]",
+		Short: "Show all settings, or a single one",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				value, err := getConfigValue(cfg, args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Println(value)
+				return nil
+			}
+
+			for _, key := range configKeys {
+				value, _ := getConfigValue(cfg, key)
+				fmt.Printf("%s: %s\n", key, value)
+			}
+			clusters := make([]string, 0, len(cfg.ClusterCommands))
+			for cluster := range cfg.ClusterCommands {
+				clusters = append(clusters, cluster)
+			}
+			sort.Strings(clusters)
+			for _, cluster := range clusters {
+				fmt.Printf("%s%s: %s\n", clusterCommandPrefix, cluster, cfg.ClusterCommands[cluster])
+			}
+			patterns := make([]string, 0, len(cfg.ContainerCommands))
+			for pattern := range cfg.ContainerCommands {
+				patterns = append(patterns, pattern)
+			}
+			sort.Strings(patterns)
+			for _, pattern := range patterns {
+				fmt.Printf("%s%s: %s\n", containerCommandPrefix, pattern, cfg.ContainerCommands[pattern])
+			}
+			return nil
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a setting",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if err := setConfigValue(cfg, args[0], args[1]); err != nil {
+				return err
+			}
+			if err := saveConfig(cfg); err != nil {
+				return fmt.Errorf("could not save config: %v", err)
+			}
+			fmt.Printf("%s Saved %s = %s\n", okIcon(), args[0], args[1])
+			return nil
+		},
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the location of the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := configPath()
+			if err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+
+	configCmd.AddCommand(getCmd, setCmd, pathCmd)
+	return configCmd
+}
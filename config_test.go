@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// withSavedRegion points the config file at a temporary directory holding savedRegion and returns
+// the loaded config
+func withSavedRegion(t *testing.T, savedRegion string) *appConfig {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AWS_PROFILE", "")
+	if err := os.MkdirAll(filepath.Join(dir, "ecs-session"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ecs-session", "config.yaml"), []byte("region: "+savedRegion+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	previousRegion, previousProfile, previousNoSave := region, profile, noSave
+	region, profile = "", ""
+	t.Cleanup(func() { region, profile, noSave = previousRegion, previousProfile, previousNoSave })
+
+	settings, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestNoSaveRegionIgnoresSavedRegion(t *testing.T) {
+	settings := withSavedRegion(t, "eu-north-1")
+
+	noSave = false
+	if got := loadDefaultRegion(settings); got != "eu-north-1" {
+		t.Fatalf("loadDefaultRegion(settings) = %q, want the saved eu-north-1", got)
+	}
+	if _, got := checkDoctorRegion(settings); got != "eu-north-1" {
+		t.Errorf("doctor uses region %q, want the saved eu-north-1", got)
+	}
+
+	noSave = true
+	if got := loadDefaultRegion(settings); got != "" {
+		t.Errorf("loadDefaultRegion(settings) with --no-save-region = %q, want none", got)
+	}
+	if check, got := checkDoctorRegion(settings); got != "" || check.OK {
+		t.Errorf("doctor with --no-save-region uses region %q, want none", got)
+	}
+	if _, err := newListClient(settings); err == nil || exitCodeFor(err) != exitConfigError {
+		t.Errorf("list with --no-save-region and no --region: got %v, want a config error", err)
+	}
+}
+
+func TestPageSizeZeroShowsAll(t *testing.T) {
+	cfg, err := decodeConfig([]byte("page_size: 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PageSize == nil || *cfg.PageSize != 0 {
+		t.Fatalf("page_size: 0 decoded as %v, want 0", cfg.PageSize)
+	}
+
+	cfg = &appConfig{}
+	for _, tt := range []struct {
+		value string
+		want  string
+	}{
+		{value: "0", want: "0"},
+		{value: "15", want: "15"},
+		{value: "", want: ""},
+	} {
+		if err := setConfigValue(cfg, "page-size", tt.value); err != nil {
+			t.Fatalf("config set page-size %q: %v", tt.value, err)
+		}
+		if got, _ := getConfigValue(cfg, "page-size"); got != tt.want {
+			t.Errorf("after config set page-size %q, get gives %q, want %q", tt.value, got, tt.want)
+		}
+	}
+	if err := setConfigValue(cfg, "page-size", "-1"); err == nil {
+		t.Error("a negative page size was accepted")
+	}
+
+	size := 0
+	data, err := yaml.Marshal(&appConfig{PageSize: &size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "page_size: 0\n" {
+		t.Errorf("page size 0 saved as %q, want it kept", data)
+	}
+}
+
+func TestDecodeConfigReportsLines(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "unknown key",
+			data: "region: eu-west-1\npage_sise: 3\n",
+			want: []string{"line 2: ", `unknown setting "page_sise"`},
+		},
+		{
+			name: "wrong type",
+			data: "region: eu-west-1\nprofile: dev\npage_size: lots\n",
+			want: []string{"line 3: ", "lots"},
+		},
+		{
+			name: "bad theme",
+			data: "region: eu-west-1\n\ntheme: neon\n",
+			want: []string{"line 3: ", `theme must be one of`, `"neon"`},
+		},
+		{
+			name: "bad value after valid ones",
+			data: "region: eu-west-1\npage_size: 10\nprotected_tag: production\n",
+			want: []string{"line 3: ", "protected_tag must be key=value or none"},
+		},
+		{
+			name: "several problems",
+			data: "page_size: -1\ntheme: neon\n",
+			want: []string{"line 1: page_size must not be negative", "line 2: theme must be one of"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeConfig([]byte(tt.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q doesn't contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := decodeConfig([]byte("region: eu-west-1\npage_size: 0\ntheme: " + themeNames()[0] + "\n")); err != nil {
+		t.Errorf("a valid config was rejected: %v", err)
+	}
+}
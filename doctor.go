@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+// doctorCheck is the outcome of one setup check. Critical failures make doctor exit non-zero.
+type doctorCheck struct {
+	Name     string
+	OK       bool
+	Critical bool
+	Detail   string
+}
+
+// newDoctorCommand builds the `doctor` subcommand that diagnoses common setup problems
+func newDoctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "🩺 Check that the AWS CLI, session-manager-plugin and credentials are set up",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checks := []doctorCheck{
+				checkAWSCLI(),
+				checkSessionManagerPlugin(),
+			}
+			checkRegion, region := checkDoctorRegion(fileConfig)
+			checks = append(checks, checkRegion, checkCredentials(region))
+
+			failed := 0
+			for _, check := range checks {
+				switch {
+				case check.OK:
+					fmt.Printf("%s %s: %s\n", okIcon(), check.Name, check.Detail)
+				case check.Critical:
+					failed++
+					fmt.Printf("%s %s: %s\n", errorIcon(), check.Name, check.Detail)
+				default:
+					fmt.Printf("%s %s: %s\n", warnIcon(), check.Name, check.Detail)
+				}
+			}
+
+			if failed > 0 {
+				return withExitCode(exitGeneralError, fmt.Errorf("%d critical check(s) failed", failed))
+			}
+			fmt.Printf("%s Ready to start sessions\n", launchIcon())
+			return nil
+		},
+	}
+}
+
+// checkAWSCLI checks that the aws CLI is installed and new enough for execute-command
+func checkAWSCLI() doctorCheck {
+	check := doctorCheck{Name: "AWS CLI", Critical: true}
+	if _, err := exec.LookPath("aws"); err != nil {
+		check.Detail = "aws not found in PATH, install it from https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"
+		// The native backend only needs the plugin, but --logs still needs the CLI
+		if _, err := exec.LookPath("session-manager-plugin"); err == nil {
+			check.Critical = false
+			check.Detail += ", or start sessions with --native, which only needs session-manager-plugin"
+		}
+		return check
+	}
+	version, err := awsCLIVersion()
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	if err := checkAWSCLIVersion(); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	check.OK = true
+	check.Detail = "version " + formatVersion(version)
+	return check
+}
+
+// checkSessionManagerPlugin checks that the plugin the aws CLI needs for execute-command is installed
+func checkSessionManagerPlugin() doctorCheck {
+	check := doctorCheck{Name: "Session Manager plugin", Critical: true}
+	if _, err := exec.LookPath("session-manager-plugin"); err != nil {
+		check.Detail = "session-manager-plugin not found in PATH, install it from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html"
+		return check
+	}
+	check.OK = true
+	check.Detail = "installed"
+	// The plugin prints its version on --version; older releases don't support it
+	if output, err := exec.Command("session-manager-plugin", "--version").Output(); err == nil {
+		check.Detail = "version " + strings.TrimSpace(string(output))
+	}
+	return check
+}
+
+// checkDoctorRegion finds the region the session would use, without prompting
+func checkDoctorRegion(settings *appConfig) (doctorCheck, string) {
+	check := doctorCheck{Name: "Region"}
+	resolved := region
+	source := "--region"
+	if resolved == "" {
+		resolved, source = profileRegion(), "profile "+activeProfile()
+	}
+	if resolved == "" {
+		resolved, source = loadDefaultRegion(settings), "saved default"
+	}
+	if resolved == "" {
+		check.Detail = "none configured, you will be asked to choose one"
+		return check, ""
+	}
+	check.OK = true
+	check.Detail = fmt.Sprintf("%s (from %s)", resolved, source)
+	return check, resolved
+}
+
+// checkCredentials checks that credentials resolve and are accepted by calling sts:GetCallerIdentity
+func checkCredentials(resolvedRegion string) doctorCheck {
+	check := doctorCheck{Name: "AWS credentials", Critical: true}
+	// STS answers in every region, so any region will do when none is configured
+	if resolvedRegion == "" {
+		resolvedRegion = "us-east-1"
+	}
+	cfg, err := loadAWSConfig(resolvedRegion)
+	if err != nil {
+		check.Detail = fmt.Sprintf("unable to load SDK config: %v", err)
+		return check
+	}
+	identity, err := callerIdentity(cfg)
+	if err != nil {
+		check.Detail = fmt.Sprintf("unable to verify credentials: %v", err)
+		return check
+	}
+	check.OK = true
+	check.Detail = fmt.Sprintf("%s (account %s)", aws.ToString(identity.Arn), aws.ToString(identity.Account))
+	return check
+}
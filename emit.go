@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"syscall"
+)
+
+// emitSelectionPath is the --emit-selection file or FIFO the completed selection is written to
+var emitSelectionPath string
+
+// emittedSelection is the JSON written to --emit-selection
+type emittedSelection struct {
+	Account    string   `json:"account"`
+	Region     string   `json:"region"`
+	Cluster    string   `json:"cluster"`
+	Service    string   `json:"service,omitempty"`
+	Task       string   `json:"task"`
+	Containers []string `json:"containers"`
+	Commands   []string `json:"commands"`
+}
+
+// emitSelection writes the selection as one line of JSON to --emit-selection, just before the
+// session starts. A FIFO is opened without blocking, so a tool that isn't reading only gets a
+// warning instead of holding up the session.
+func emitSelection(sel selection, commands []string) {
+	if emitSelectionPath == "" {
+		return
+	}
+
+	data, err := json.Marshal(emittedSelection{
+		Account:    sel.Account,
+		Region:     sel.Region,
+		Cluster:    sel.Cluster,
+		Service:    sel.Service,
+		Task:       sel.Task,
+		Containers: sel.Containers,
+		Commands:   commands,
+	})
+	if err != nil {
+		log.Printf("%s Unable to encode the selection: %v", warnIcon(), err)
+		return
+	}
+
+	file, err := os.OpenFile(emitSelectionPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NONBLOCK, 0o600)
+	if err != nil {
+		log.Printf("%s Unable to write the selection to %s (is anything reading it?): %v", warnIcon(), emitSelectionPath, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Printf("%s Unable to write the selection to %s: %v", warnIcon(), emitSelectionPath, err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix starts the environment variables that stand in for flags, e.g. ECS_SESSION_CLUSTER
+const envPrefix = "ECS_SESSION_"
+
+// flagEnvVar returns the environment variable of a flag: --cluster-filter is ECS_SESSION_CLUSTER_FILTER
+func flagEnvVar(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvDefaults sets every flag not given on the command line from its ECS_SESSION_ variable.
+// The flag then counts as set, so the config file only fills in what neither of them gives.
+// Repeatable flags take a comma-separated list, or a single value for --arg and --tag.
+func applyEnvDefaults(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+		value, ok := os.LookupEnv(flagEnvVar(flag.Name))
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s=%q: %v", flagEnvVar(flag.Name), value, setErr)
+		}
+	})
+	return err
+}
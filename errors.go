@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// Error categories of the AWS helpers, for errors.Is. The errors they return wrap the SDK error,
+// so errors.As still finds the smithy.APIError underneath.
+var (
+	errAccessDenied = errors.New("access denied")
+	errCredentials  = errors.New("missing or rejected credentials")
+	errThrottled    = errors.New("request throttled")
+	errNoClusters   = errors.New("no clusters found")
+)
+
+// accessDeniedCodes are the error codes AWS APIs use for authorization failures
+var accessDeniedCodes = map[string]bool{
+	"AccessDeniedException": true,
+	"AccessDenied":          true,
+	"UnauthorizedOperation": true,
+}
+
+// isAccessDenied reports whether err is an AWS authorization failure
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if accessDeniedCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	// ECS reports some authorization failures as a ClientException
+	return apiErr.ErrorCode() == "ClientException" && strings.Contains(apiErr.ErrorMessage(), "not authorized")
+}
+
+// accessDeniedError names the IAM action that was missing, keeping the original error for errors.As
+type accessDeniedError struct {
+	action   string
+	resource string
+	err      error
+}
+
+func (e *accessDeniedError) Error() string {
+	return fmt.Sprintf("access denied: you need %s on %s", e.action, e.resource)
+}
+
+func (e *accessDeniedError) Unwrap() error {
+	return e.err
+}
+
+func (e *accessDeniedError) Is(target error) bool {
+	return target == errAccessDenied
+}
+
+// wrapAWSError sorts the error of a failed AWS call into one of the error categories above.
+// Authorization failures get a message naming the IAM action to request.
+func wrapAWSError(err error, action string, resource string) error {
+	switch {
+	case err == nil:
+		return nil
+	case isAccessDenied(err):
+		return &accessDeniedError{action: action, resource: resource, err: err}
+	case isCredentialsError(err):
+		return fmt.Errorf("%w: %w", errCredentials, err)
+	case isThrottled(err):
+		return fmt.Errorf("%w: %s on %s: %w", errThrottled, action, resource, err)
+	}
+	return err
+}
+
+// throttlingCodes are the error codes AWS APIs use when a caller exceeds the request rate
+var throttlingCodes = map[string]bool{
+	"ThrottlingException":      true,
+	"Throttling":               true,
+	"TooManyRequestsException": true,
+	"RequestLimitExceeded":     true,
+}
+
+// isThrottled reports whether err is an AWS rate limit error that outlasted the SDK's retries
+func isThrottled(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttlingCodes[apiErr.ErrorCode()]
+}
+
+// credentialErrorCodes are the error codes for missing, expired or invalid credentials
+var credentialErrorCodes = map[string]bool{
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidClientTokenId":        true,
+	"UnrecognizedClientException": true,
+	"InvalidSignatureException":   true,
+}
+
+// isCredentialsError reports whether err comes from credentials that couldn't be found or were rejected
+func isCredentialsError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && credentialErrorCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	// The SDK doesn't export a type for credential resolution failures
+	message := err.Error()
+	return strings.Contains(message, "failed to retrieve credentials") || strings.Contains(message, "failed to refresh cached credentials")
+}
+
+// errTaskGone is returned when execute-command fails because the task stopped or was replaced
+var errTaskGone = errors.New("the task is no longer running")
+
+// taskGoneMessages are lower-cased fragments of AWS CLI errors for a task that can't be reached anymore
+var taskGoneMessages = []string{
+	"targetnotconnectedexception",
+	"task not found",
+	"task is not found",
+	"task is stopped",
+	"is not in a running state",
+}
+
+// isTaskGone reports whether the AWS CLI's stderr says the task has stopped or disappeared
+func isTaskGone(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, message := range taskGoneMessages {
+		if strings.Contains(stderr, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKMSFailure reports whether the AWS CLI's stderr points at the KMS key used to encrypt exec sessions
+func isKMSFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	return strings.Contains(stderr, "kms:") || strings.Contains(stderr, "kmsaccessdenied") ||
+		strings.Contains(stderr, "kmsinvalidstate") || strings.Contains(stderr, "kms key")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestWrapAWSError(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		message  string
+		is       error
+		exitCode int
+	}{
+		{name: "access denied", code: "AccessDeniedException", message: "User is not authorized", is: errAccessDenied, exitCode: exitAuthError},
+		{name: "ECS client exception", code: "ClientException", message: "User is not authorized to perform ecs:ListClusters", is: errAccessDenied, exitCode: exitAuthError},
+		{name: "expired token", code: "ExpiredToken", message: "The security token included in the request is expired", is: errCredentials, exitCode: exitAuthError},
+		{name: "throttling", code: "ThrottlingException", message: "Rate exceeded", is: errThrottled, exitCode: exitGeneralError},
+		{name: "cluster not found", code: "ClusterNotFoundException", message: "Cluster not found.", exitCode: exitGeneralError},
+	}
+	categories := []error{errAccessDenied, errCredentials, errThrottled, errNoClusters}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := &smithy.GenericAPIError{Code: tt.code, Message: tt.message}
+			// The SDK wraps the API error in its own operation error
+			err := wrapAWSError(fmt.Errorf("operation error ECS: ListClusters: %w", apiErr), "ecs:ListClusters", "all clusters")
+
+			for _, category := range categories {
+				if got, want := errors.Is(err, category), category == tt.is; got != want {
+					t.Errorf("errors.Is(err, %q) = %t, want %t", category, got, want)
+				}
+			}
+			var unwrapped smithy.APIError
+			if !errors.As(err, &unwrapped) || unwrapped.ErrorCode() != tt.code {
+				t.Errorf("errors.As doesn't find the %s API error in %v", tt.code, err)
+			}
+			if got := awsExitCode(err); got != tt.exitCode {
+				t.Errorf("awsExitCode() = %d, want %d", got, tt.exitCode)
+			}
+		})
+	}
+
+	if wrapAWSError(nil, "ecs:ListClusters", "all clusters") != nil {
+		t.Error("a nil error came back wrapped")
+	}
+	if err := wrapAWSError(&smithy.GenericAPIError{Code: "AccessDenied"}, "ecs:DescribeServices", "service api"); err.Error() != "access denied: you need ecs:DescribeServices on service api" {
+		t.Errorf("access denied message = %q", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+// Exit codes, so scripts can tell failure categories apart
+const (
+	exitGeneralError = 1 // anything not covered below
+	exitConfigError  = 2 // invalid flags, config file or SDK configuration
+	exitAuthError    = 3 // missing or rejected credentials, or missing IAM permissions
+	exitNoResources  = 4 // nothing to choose from, e.g. no clusters in the region
+	exitUserAbort    = 5 // the user cancelled, e.g. by closing stdin
+)
+
+// exitCodeError attaches an exit code to an error returned from a cobra command
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode wraps err so main exits with the given code
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+// exitCodeFor returns the exit code main should use for an error returned by a command.
+// Errors without an explicit code come from flag and argument parsing or the config file.
+func exitCodeFor(err error) int {
+	var codeErr *exitCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.code
+	}
+	return exitConfigError
+}
+
+// awsExitCode picks the exit code for a failed AWS call
+func awsExitCode(err error) int {
+	switch {
+	case errors.Is(err, errAccessDenied), errors.Is(err, errCredentials):
+		return exitAuthError
+	case errors.Is(err, errNoClusters):
+		return exitNoResources
+	case isAccessDenied(err), isCredentialsError(err):
+		// Errors that didn't go through wrapAWSError
+		return exitAuthError
+	}
+	return exitGeneralError
+}
+
+// fatal logs the message and exits with the given code
+func fatal(code int, format string, args ...any) {
+	log.Printf(format, args...)
+	exitWith(code)
+}
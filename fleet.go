@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+var (
+	// fleetMode runs the command in the same-named service of several clusters, picked from a menu
+	fleetMode bool
+	// allClusters is fleetMode on every cluster matching --cluster-filter, without the menu
+	allClusters bool
+)
+
+// fleetTarget is the task and container a fleet run connects to in one cluster
+type fleetTarget struct {
+	Task      string
+	Container string
+}
+
+// runFleet runs one command in the service of every chosen cluster, one cluster after another.
+// Non-interactive runs print each line of output labelled with its cluster.
+func runFleet(client *ecs.Client, sel selection, settings *appConfig, scriptCommand string, clusterPattern *regexp.Regexp) {
+	clusterNames, err := withSpinner("Loading clusters...", func() ([]string, error) {
+		return listClusters(client)
+	})
+	if err != nil && !errors.Is(err, errNoClusters) {
+		fatal(awsExitCode(err), "%s Unable to list clusters: %v", errorIcon(), err)
+	}
+	clusterNames = filterNames(clusterNames, clusterPattern)
+	if clusterNames, err = filterClustersByTags(client, clusterNames); err != nil {
+		fatal(awsExitCode(err), "%s Unable to read cluster tags: %v", errorIcon(), err)
+	}
+	if len(clusterNames) == 0 {
+		fatal(exitNoResources, "%s No clusters found in region %s%s", errorIcon(), sel.Region, tagFilterNote())
+	}
+	if !allClusters {
+		choice := chooseLabeledOptionsWithBack("cluster", clusterNames, clusterNames, -1, true)
+		if choice.Action != menuPicked {
+			fatal(exitUserAbort, "%s No clusters chosen", infoIcon())
+		}
+		clusterNames = choice.Values
+	}
+
+	serviceName := resourceName(serviceFlag, "service")
+	if serviceName == "" {
+		serviceName = requireInput(fmt.Sprintf("%s Enter the service to run in each cluster: ", promptIcon()))
+	}
+
+	command := scriptCommand
+	if command == "" {
+		command = commandFlag
+	}
+	if command == "" {
+		command = settings.DefaultCommand
+	}
+	if command == "" {
+		command = chooseCommand("")
+	}
+	switch command {
+	case viewLogsCommand:
+		fatal(exitConfigError, "%s Logs can't be followed in several clusters at once", errorIcon())
+	case detectShellCommand:
+		command = "sh"
+	}
+	if err := checkDeniedCommand(command); err != nil {
+		fatal(exitConfigError, "%s Refusing to start the session: %v", errorIcon(), err)
+	}
+
+	// Find every target first, so the run is confirmed once for the whole fleet
+	var failed, targetClusters []string
+	var targets []*fleetTarget
+	for _, clusterName := range clusterNames {
+		target, err := withSpinner(fmt.Sprintf("Finding a task of %s in %s...", serviceName, clusterName), func() (*fleetTarget, error) {
+			return findFleetTarget(client, clusterName, serviceName)
+		})
+		if err != nil {
+			log.Printf("%s [%s] Skipped: %v", warnIcon(), clusterName, err)
+			failed = append(failed, clusterName)
+			continue
+		}
+		if target == nil {
+			statusf("%s [%s] No service %s, skipped\n", infoIcon(), clusterName, serviceName)
+			continue
+		}
+		if !dryRun && !confirmProtectedAccess(client, settings, clusterName, serviceName) {
+			failed = append(failed, clusterName)
+			continue
+		}
+		targetClusters = append(targetClusters, clusterName)
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 && len(failed) == 0 {
+		fatal(exitNoResources, "%s None of the clusters has a service %s", errorIcon(), serviceName)
+	}
+	if len(targets) > 0 && !dryRun && !confirmFleet(serviceName, command, targetClusters, targets) {
+		fatal(exitUserAbort, "%s Fleet run cancelled", infoIcon())
+	}
+
+	succeeded := 0
+	for i, clusterName := range targetClusters {
+		if err := runFleetTarget(sel.Region, clusterName, targets[i], command); err != nil {
+			log.Printf("%s [%s] %v", errorIcon(), clusterName, err)
+			failed = append(failed, clusterName)
+			continue
+		}
+		succeeded++
+	}
+
+	if len(failed) > 0 {
+		fatal(exitGeneralError, "%s Ran in %d cluster(s), failed in: %s", errorIcon(), succeeded, strings.Join(failed, ", "))
+	}
+	statusf("%s Ran in %d cluster(s)\n", okIcon(), succeeded)
+}
+
+// confirmFleet lists where the fleet run connects and waits for Enter or y, unless --yes was passed
+func confirmFleet(serviceName string, command string, clusterNames []string, targets []*fleetTarget) bool {
+	if assumeYes {
+		return true
+	}
+	promptf("%s About to run '%s' in service %s of %d cluster(s):\n", launchIcon(), command, serviceName, len(targets))
+	for i, target := range targets {
+		promptf("   %s: %s in task %s\n", clusterNames[i], target.Container, target.Task[strings.LastIndex(target.Task, "/")+1:])
+	}
+	return confirmDefaultYes(fmt.Sprintf("%s Press Enter or y to go ahead, anything else to cancel: ", promptIcon()))
+}
+
+// runFleetTarget runs the command in one cluster's target. Failures are returned rather than
+// ending the run, so the other clusters still get their turn.
+func runFleetTarget(region string, clusterName string, target *fleetTarget, command string) error {
+	if dryRun || interactiveSession {
+		statusf("%s [%s] %s in task %s\n", launchIcon(), clusterName, target.Container, target.Task)
+		return tryAWSSession(region, clusterName, target.Task, target.Container, command)
+	}
+
+	output, err := runNonInteractive(region, clusterName, target.Task, target.Container, command)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fmt.Printf("[%s] %s\n", clusterName, scanner.Text())
+	}
+	return err
+}
+
+// findFleetTarget picks the oldest running task of the service with execute-command enabled and
+// the container given with --container, or else the first essential one. It returns nil when the
+// cluster has no such service.
+func findFleetTarget(client *ecs.Client, clusterName string, serviceName string) (*fleetTarget, error) {
+	serviceNames, err := listServices(client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(serviceNames, serviceName) {
+		return nil, nil
+	}
+
+	tasks, err := listServiceTasks(client, clusterName, serviceName, nil)
+	if err != nil {
+		return nil, err
+	}
+	taskArn := ""
+	for _, task := range tasks {
+		if task.EnableExecuteCommand && aws.ToString(task.LastStatus) == "RUNNING" {
+			taskArn = aws.ToString(task.TaskArn)
+			break
+		}
+	}
+	if taskArn == "" {
+		return nil, fmt.Errorf("no running task of %s has execute-command enabled", serviceName)
+	}
+
+	containerNames, _, err := listContainers(client, clusterName, taskArn)
+	if err != nil {
+		return nil, err
+	}
+	definedOrder, essential, err := containerDefinitions(client, clusterName, taskArn)
+	if err != nil {
+		return nil, err
+	}
+	sortByDefinedOrder(containerNames, definedOrder)
+	if len(containerNames) == 0 {
+		return nil, fmt.Errorf("task %s has no running container", taskArn)
+	}
+
+	container := containerNames[0]
+	if containerFlag != "" {
+		matches := matchContainers(containerFlag, containerNames, definedOrder)
+		if len(matches) != 1 {
+			return nil, fmt.Errorf("--container %s matches %d containers", containerFlag, len(matches))
+		}
+		container = matches[0]
+	} else {
+		for _, name := range containerNames {
+			if essential[name] {
+				container = name
+				break
+			}
+		}
+	}
+	return &fleetTarget{Task: taskArn, Container: container}, nil
+}
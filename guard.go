@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// defaultProtectedTag marks clusters and services that need a typed confirmation before connecting
+const defaultProtectedTag = "Environment=production"
+
+// protectedTag returns the tag key and value that mark protected resources, or ok=false when the
+// check is turned off with `config set protected-tag none`
+func protectedTag(settings *appConfig) (key string, value string, ok bool) {
+	tag := settings.ProtectedTag
+	if tag == "" {
+		tag = defaultProtectedTag
+	}
+	if tag == "none" {
+		return "", "", false
+	}
+	key, value, _ = strings.Cut(tag, "=")
+	return key, value, true
+}
+
+// protectedResource returns a description of the cluster or service carrying the protected tag, or "" if neither does
+func protectedResource(client *ecs.Client, settings *appConfig, clusterName string, serviceName string) (string, error) {
+	key, value, ok := protectedTag(settings)
+	if !ok {
+		return "", nil
+	}
+
+	clusters, err := client.DescribeClusters(context.TODO(), &ecs.DescribeClustersInput{Clusters: []string{clusterName}})
+	if err != nil {
+		return "", wrapAWSError(err, "ecs:DescribeClusters", "cluster "+clusterName)
+	}
+	var descriptions, resourceArns []string
+	if len(clusters.Clusters) > 0 {
+		descriptions = append(descriptions, "cluster "+clusterName)
+		resourceArns = append(resourceArns, aws.ToString(clusters.Clusters[0].ClusterArn))
+	}
+	if serviceName != "" {
+		service, err := describeService(client, clusterName, serviceName)
+		if err != nil {
+			return "", err
+		}
+		descriptions = append(descriptions, "service "+serviceName)
+		resourceArns = append(resourceArns, aws.ToString(service.ServiceArn))
+	}
+
+	for i, resourceArn := range resourceArns {
+		output, err := client.ListTagsForResource(context.TODO(), &ecs.ListTagsForResourceInput{ResourceArn: &resourceArn})
+		if err != nil {
+			return "", wrapAWSError(err, "ecs:ListTagsForResource", descriptions[i])
+		}
+		for _, tag := range output.Tags {
+			if aws.ToString(tag.Key) == key && strings.EqualFold(aws.ToString(tag.Value), value) {
+				return fmt.Sprintf("%s (tagged %s=%s)", descriptions[i], key, aws.ToString(tag.Value)), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// confirmProtectedAccess asks the user to type the cluster name before connecting to a protected resource.
+// It returns false when the user doesn't confirm. When the tags can't be read it asks anyway.
+func confirmProtectedAccess(client *ecs.Client, settings *appConfig, clusterName string, serviceName string) bool {
+	resource, err := protectedResource(client, settings, clusterName, serviceName)
+	if err != nil {
+		// Without the tags there is no telling whether it's protected, so ask as if it were
+		log.Printf("%s Could not check the tags of cluster %s, asking for confirmation in case it is protected: %v", warnIcon(), clusterName, err)
+		resource = "cluster " + clusterName + " (tags could not be checked)"
+	}
+	if resource == "" {
+		return true
+	}
+
+	return stdio.confirmClusterName(resource, clusterName)
+}
+
+// confirmClusterName asks for the cluster name to be typed out before connecting to the protected resource
+func (p *prompter) confirmClusterName(resource string, clusterName string) bool {
+	p.printf("%s You are about to connect to a protected %s.\n", warnIcon(), resource)
+	answer := p.requireInput(fmt.Sprintf("%s Type the cluster name (%s) to continue: ", promptIcon(), clusterName))
+	if answer != clusterName {
+		p.printf("%s Cluster name doesn't match, not connecting\n", errorIcon())
+		return false
+	}
+	return true
+}
+
+// confirmLaunch shows what the session is about to connect to and waits for Enter or y, unless
+// --yes was passed. It returns false when the user answers anything else.
+func confirmLaunch(sel selection, commands []string) bool {
+	if assumeYes {
+		return true
+	}
+	return stdio.confirmLaunch(sel, commands)
+}
+
+// confirmLaunch shows the launch summary and asks to go ahead
+func (p *prompter) confirmLaunch(sel selection, commands []string) bool {
+	service := sel.Service
+	if service == "" {
+		service = "-"
+	}
+	p.printf("%s About to connect:\n", launchIcon())
+	p.printf("   Account:   %s\n", sel.Account)
+	p.printf("   Region:    %s\n", sel.Region)
+	p.printf("   Cluster:   %s\n", sel.Cluster)
+	p.printf("   Service:   %s\n", service)
+	p.printf("   Task:      %s\n", sel.Task[strings.LastIndex(sel.Task, "/")+1:])
+	for i, container := range sel.Containers {
+		command := commands[i]
+		if command == detectShellCommand {
+			command = "the detected shell"
+		}
+		p.printf("   Container: %s, running %s\n", container, command)
+	}
+	return p.confirmDefaultYes(fmt.Sprintf("%s Press Enter or y to connect, anything else to go back: ", promptIcon()))
+}
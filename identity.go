@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// sessionCredentials are the credentials the SDK resolved and validated for the session. They
+// are handed to the aws CLI, which doesn't resolve credentials exactly like the SDK does.
+var sessionCredentials aws.CredentialsProvider
+
+// exportCredentials returns env with the session credentials as AWS_ACCESS_KEY_ID and friends.
+// Environment credentials beat the profile in the aws CLI, so it uses exactly what we validated.
+func exportCredentials(env []string) []string {
+	if sessionCredentials == nil {
+		return env
+	}
+	// The provider is cached, so this only calls out when the credentials are about to expire
+	creds, err := sessionCredentials.Retrieve(context.TODO())
+	if err != nil {
+		log.Printf("%s Could not hand the session credentials to the aws CLI, it resolves its own: %v", warnIcon(), err)
+		return env
+	}
+
+	env = setEnv(env, "AWS_ACCESS_KEY_ID", creds.AccessKeyID)
+	env = setEnv(env, "AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey)
+	// A token left over from other credentials would make the CLI's requests fail
+	env = slices.DeleteFunc(env, func(entry string) bool {
+		return strings.HasPrefix(entry, "AWS_SESSION_TOKEN=")
+	})
+	if creds.SessionToken != "" {
+		env = append(env, "AWS_SESSION_TOKEN="+creds.SessionToken)
+	}
+	return env
+}
+
+// accountAliases caches the alias of each account for the lifetime of the process
+var accountAliases = map[string]string{}
+
+// callerIdentity asks STS who the credentials belong to, which also proves they are valid
+func callerIdentity(cfg aws.Config) (*sts.GetCallerIdentityOutput, error) {
+	output, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	return output, wrapAWSError(err, "sts:GetCallerIdentity", "your credentials")
+}
+
+// accountLabel names the account for the breadcrumb, e.g. "my-prod (123456789012)". Without an
+// alias, or without iam:ListAccountAliases, it is just the account ID.
+func accountLabel(cfg aws.Config, accountID string) string {
+	alias, ok := accountAliases[accountID]
+	if !ok {
+		output, err := iam.NewFromConfig(cfg).ListAccountAliases(context.TODO(), &iam.ListAccountAliasesInput{})
+		if err == nil && len(output.AccountAliases) > 0 {
+			alias = output.AccountAliases[0]
+		}
+		accountAliases[accountID] = alias
+	}
+
+	if alias == "" {
+		return accountID
+	}
+	return alias + " (" + accountID + ")"
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputFormat  string
+	watchList     bool
+	watchInterval time.Duration
+)
+
+// newListCommand builds the read-only `list` subcommand tree
+func newListCommand() *cobra.Command {
+	var clusterName, serviceName, taskID string
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "📋 List clusters, services or tasks without starting a session",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" && outputFormat != "wide" {
+				return fmt.Errorf("invalid --output %q: must be text, json or wide", outputFormat)
+			}
+			if watchInterval <= 0 {
+				return fmt.Errorf("invalid --interval %s: must be positive", watchInterval)
+			}
+			return nil
+		},
+	}
+	listCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json or wide (a table with more details)")
+	listCmd.PersistentFlags().BoolVarP(&watchList, "watch", "w", false, "👀 Redraw the listing every --interval until Ctrl+C")
+	listCmd.PersistentFlags().DurationVar(&watchInterval, "interval", 5*time.Second, "How often --watch refreshes the listing")
+
+	clustersCmd := &cobra.Command{
+		Use:   "clusters",
+		Short: "List ECS clusters",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newListClient(fileConfig)
+			if err != nil {
+				return err
+			}
+			return watch(func() error {
+				names, err := listClusters(client)
+				if errors.Is(err, errNoClusters) {
+					err = nil
+				}
+				if err != nil {
+					return withExitCode(awsExitCode(err), fmt.Errorf("unable to list clusters: %v", err))
+				}
+				if outputFormat == "wide" {
+					return printClustersWide(client, names)
+				}
+				return printNames(names)
+			})
+		},
+	}
+
+	servicesCmd := &cobra.Command{
+		Use:   "services",
+		Short: "List the services of a cluster",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newListClient(fileConfig)
+			if err != nil {
+				return err
+			}
+			cluster := resourceName(clusterName, "cluster")
+			return watch(func() error {
+				names, err := listServices(client, cluster)
+				if err != nil {
+					return withExitCode(awsExitCode(err), fmt.Errorf("unable to list services: %v", err))
+				}
+				if outputFormat == "wide" {
+					return printServicesWide(client, cluster, names)
+				}
+				return printNames(names)
+			})
+		},
+	}
+	servicesCmd.Flags().StringVar(&clusterName, "cluster", "", "Cluster name or ARN")
+	servicesCmd.MarkFlagRequired("cluster")
+
+	tasksCmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "List the tasks of a service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newListClient(fileConfig)
+			if err != nil {
+				return err
+			}
+			cluster := resourceName(clusterName, "cluster")
+			return watch(func() error {
+				if outputFormat == "wide" {
+					tasks, err := listServiceTasks(client, cluster, resourceName(serviceName, "service"), nil)
+					if err != nil {
+						return withExitCode(awsExitCode(err), fmt.Errorf("unable to list tasks: %v", err))
+					}
+					return printTasksWide(tasks)
+				}
+				arns, err := listTasks(client, cluster, resourceName(serviceName, "service"))
+				if err != nil {
+					return withExitCode(awsExitCode(err), fmt.Errorf("unable to list tasks: %v", err))
+				}
+				return printNames(arns)
+			})
+		},
+	}
+	tasksCmd.Flags().StringVar(&clusterName, "cluster", "", "Cluster name or ARN")
+	tasksCmd.Flags().StringVar(&serviceName, "service", "", "Service name or ARN")
+	tasksCmd.MarkFlagRequired("cluster")
+	tasksCmd.MarkFlagRequired("service")
+
+	containersCmd := &cobra.Command{
+		Use:   "containers",
+		Short: "List the running containers of a task",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := newListClient(fileConfig)
+			if err != nil {
+				return err
+			}
+			cluster := resourceName(clusterName, "cluster")
+			return watch(func() error {
+				// Follow the task definition like the container menu does, so positions agree
+				definedOrder, _, err := containerDefinitions(client, cluster, taskID)
+				if err != nil {
+					log.Printf("%s Could not read the task definition: %v", warnIcon(), err)
+				}
+				if outputFormat == "wide" {
+					tasks, err := describeTasks(client, cluster, []string{taskID})
+					if err != nil {
+						return withExitCode(awsExitCode(err), fmt.Errorf("unable to describe task: %v", err))
+					}
+					if len(tasks) == 0 {
+						return withExitCode(exitNoResources, fmt.Errorf("task %s not found in cluster %s", taskID, cluster))
+					}
+					return printContainersWide(tasks[0], definedOrder)
+				}
+				names, _, err := listContainers(client, cluster, taskID)
+				if err != nil {
+					return withExitCode(awsExitCode(err), fmt.Errorf("unable to list containers: %v", err))
+				}
+				sortByDefinedOrder(names, definedOrder)
+				return printNames(names)
+			})
+		},
+	}
+	containersCmd.Flags().StringVar(&clusterName, "cluster", "", "Cluster name or ARN")
+	containersCmd.Flags().StringVar(&taskID, "task", "", "Task ID or ARN")
+	containersCmd.MarkFlagRequired("cluster")
+	containersCmd.MarkFlagRequired("task")
+
+	listCmd.AddCommand(clustersCmd, servicesCmd, tasksCmd, containersCmd)
+	return listCmd
+}
+
+// watch runs list once, or with --watch redraws its output every --interval until interrupted.
+// While watching, a failed refresh is shown and retried instead of ending the watch.
+func watch(list func() error) error {
+	if !watchList {
+		return list()
+	}
+	for {
+		clearScreen()
+		fmt.Printf("Every %s, last refreshed %s (Ctrl+C to stop)\n\n", watchInterval, time.Now().Format("15:04:05"))
+		if err := list(); err != nil {
+			log.Printf("%s %v", errorIcon(), err)
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// newListClient builds an ECS client without prompting, using --region or the saved default unless
+// --no-save-region is given
+func newListClient(settings *appConfig) (*ecs.Client, error) {
+	listRegion := region
+	if listRegion == "" {
+		listRegion = profileRegion()
+	}
+	if listRegion == "" {
+		listRegion = loadDefaultRegion(settings)
+	}
+	if listRegion == "" && noSave {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("no region given: pass --region, --no-save-region leaves the saved default out"))
+	}
+	if listRegion == "" {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("no region given: pass --region or save a default region first"))
+	}
+
+	cfg, err := loadAWSConfig(listRegion)
+	if err != nil {
+		return nil, withExitCode(exitConfigError, fmt.Errorf("unable to load SDK config: %v", err))
+	}
+	return ecs.NewFromConfig(cfg), nil
+}
+
+// printNames writes the names one per line, or as a JSON array with --output json
+func printNames(names []string) error {
+	if outputFormat == "json" {
+		if names == nil {
+			names = []string{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(names)
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// printClustersWide prints a table of the clusters with their service and task counts, in the
+// order of names
+func printClustersWide(client *ecs.Client, names []string) error {
+	const batchSize = 100
+
+	// DescribeClusters answers in its own order, so collect the rows first
+	clusters := make(map[string]types.Cluster, len(names))
+	for start := 0; start < len(names); start += batchSize {
+		end := min(start+batchSize, len(names))
+		output, err := client.DescribeClusters(context.TODO(), &ecs.DescribeClustersInput{Clusters: names[start:end]})
+		if err != nil {
+			return withExitCode(awsExitCode(err), fmt.Errorf("unable to describe clusters: %v", wrapAWSError(err, "ecs:DescribeClusters", "clusters")))
+		}
+		for _, cluster := range output.Clusters {
+			clusters[aws.ToString(cluster.ClusterName)] = cluster
+		}
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tSTATUS\tSERVICES\tRUNNING\tPENDING")
+	for _, name := range names {
+		cluster, ok := clusters[name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\n", name, aws.ToString(cluster.Status),
+			cluster.ActiveServicesCount, cluster.RunningTasksCount, cluster.PendingTasksCount)
+	}
+	return writer.Flush()
+}
+
+// printServicesWide prints a table of the services with their task counts, launch type and exec
+// setting, in the order of names
+func printServicesWide(client *ecs.Client, clusterName string, names []string) error {
+	// DescribeServices takes at most 10 services per call
+	const batchSize = 10
+
+	// DescribeServices answers in its own order, so collect the rows first
+	services := make(map[string]types.Service, len(names))
+	for start := 0; start < len(names); start += batchSize {
+		end := min(start+batchSize, len(names))
+		output, err := client.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
+			Cluster:  &clusterName,
+			Services: names[start:end],
+		})
+		if err != nil {
+			return withExitCode(awsExitCode(err), fmt.Errorf("unable to describe services: %v", wrapAWSError(err, "ecs:DescribeServices", "services in cluster "+clusterName)))
+		}
+		for _, service := range output.Services {
+			services[aws.ToString(service.ServiceName)] = service
+		}
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tRUNNING\tDESIRED\tPENDING\tLAUNCH TYPE\tEXEC")
+	for _, name := range names {
+		service, ok := services[name]
+		if !ok {
+			continue
+		}
+		launchType := string(service.LaunchType)
+		if launchType == "" && len(service.CapacityProviderStrategy) > 0 {
+			launchType = "capacity provider"
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%s\t%t\n", name, service.RunningCount,
+			service.DesiredCount, service.PendingCount, launchType, service.EnableExecuteCommand)
+	}
+	return writer.Flush()
+}
+
+// printTasksWide prints a table of the tasks with their task definition, status, age and private IP
+func printTasksWide(tasks []types.Task) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TASK\tTASK DEFINITION\tSTATUS\tAGE\tIP")
+	for _, task := range tasks {
+		taskArn := aws.ToString(task.TaskArn)
+		taskDefinition := aws.ToString(task.TaskDefinitionArn)
+
+		age := "-"
+		if task.StartedAt != nil {
+			age = time.Since(*task.StartedAt).Round(time.Second).String()
+		}
+		ip := taskPrivateIP(task)
+		if ip == "" {
+			ip = "-"
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", taskArn[strings.LastIndex(taskArn, "/")+1:],
+			taskDefinition[strings.LastIndex(taskDefinition, "/")+1:], aws.ToString(task.LastStatus), age, ip)
+	}
+	return writer.Flush()
+}
+
+// printContainersWide prints a table of all the task's containers, running or not, with their health and
+// image, in task definition order
+func printContainersWide(task types.Task, definedOrder []string) error {
+	containers := make(map[string]types.Container, len(task.Containers))
+	names := make([]string, 0, len(task.Containers))
+	for _, container := range task.Containers {
+		containers[aws.ToString(container.Name)] = container
+		names = append(names, aws.ToString(container.Name))
+	}
+	sortByDefinedOrder(names, definedOrder)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tSTATUS\tHEALTH\tIMAGE")
+	for _, name := range names {
+		container := containers[name]
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", aws.ToString(container.Name), aws.ToString(container.LastStatus),
+			container.HealthStatus, aws.ToString(container.Image))
+	}
+	return writer.Flush()
+}
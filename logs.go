@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// viewLogsCommand is returned by chooseCommand when the user asks for logs instead of a shell
+const viewLogsCommand = "LOGS"
+
+// containerLogStream describes where a container's awslogs output ends up in CloudWatch
+type containerLogStream struct {
+	Group  string
+	Stream string
+	Region string
+}
+
+// findContainerLogStream resolves the CloudWatch log group and stream of a container from its task definition
+func findContainerLogStream(client *ecs.Client, region string, clusterName string, taskArn string, containerName string) (*containerLogStream, error) {
+	taskDef, err := describeTaskDefinition(client, clusterName, taskArn)
+	if err != nil {
+		return nil, err
+	}
+
+	var logConfig *types.LogConfiguration
+	for _, containerDef := range taskDef.ContainerDefinitions {
+		if aws.ToString(containerDef.Name) == containerName {
+			logConfig = containerDef.LogConfiguration
+			break
+		}
+	}
+	if logConfig == nil {
+		return nil, fmt.Errorf("container %s has no log configuration", containerName)
+	}
+	if logConfig.LogDriver != types.LogDriverAwslogs {
+		return nil, fmt.Errorf("container %s uses the %s log driver, only awslogs is supported", containerName, logConfig.LogDriver)
+	}
+
+	group := logConfig.Options["awslogs-group"]
+	prefix := logConfig.Options["awslogs-stream-prefix"]
+	if group == "" || prefix == "" {
+		return nil, fmt.Errorf("container %s is missing awslogs-group or awslogs-stream-prefix", containerName)
+	}
+
+	// awslogs streams are named <prefix>/<container-name>/<task-id>
+	taskArnParts := strings.Split(taskArn, "/")
+	taskID := taskArnParts[len(taskArnParts)-1]
+
+	logRegion := logConfig.Options["awslogs-region"]
+	if logRegion == "" {
+		logRegion = region
+	}
+
+	return &containerLogStream{
+		Group:  group,
+		Stream: fmt.Sprintf("%s/%s/%s", prefix, containerName, taskID),
+		Region: logRegion,
+	}, nil
+}
+
+// tailContainerLogs follows the container's log stream using the AWS CLI
+func tailContainerLogs(logStream *containerLogStream) {
+	args := []string{"logs", "tail", logStream.Group,
+		"--log-stream-names", logStream.Stream,
+		"--follow",
+		"--region", logStream.Region}
+
+	cmd := exec.Command("aws", args...)
+
+	cmd.Env = childEnv()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	statusf("%s Tailing %s in log group %s (Ctrl+C to stop)...\n", launchIcon(), logStream.Stream, logStream.Group)
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("%s Failed to tail logs: %v\n", errorIcon(), err)
+	}
+}
+
+// containerLogTail is the number of log events --container-log-tail shows before a session, 0 for none
+var containerLogTail int
+
+// logEvent is one event of `aws logs get-log-events` output
+type logEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// recentLogEvents fetches the last count events of the container's log stream using the AWS CLI
+func recentLogEvents(logStream *containerLogStream, count int) ([]logEvent, error) {
+	args := []string{"logs", "get-log-events",
+		"--log-group-name", logStream.Group,
+		"--log-stream-name", logStream.Stream,
+		"--limit", strconv.Itoa(count),
+		"--no-start-from-head",
+		"--region", logStream.Region,
+		"--output", "json"}
+
+	cmd := exec.Command("aws", args...)
+	cmd.Env = childEnv()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var response struct {
+		Events []logEvent `json:"events"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("unexpected aws logs get-log-events output: %v", err)
+	}
+	return response.Events, nil
+}
+
+// printLogPreview shows the container's last --container-log-tail log events before the session
+// starts. The preview is only context, so failures are warnings.
+func printLogPreview(client *ecs.Client, region string, clusterName string, taskArn string, containerName string) {
+	if containerLogTail <= 0 {
+		return
+	}
+
+	logStream, err := findContainerLogStream(client, region, clusterName, taskArn, containerName)
+	if err != nil {
+		log.Printf("%s No log preview: %v", warnIcon(), err)
+		return
+	}
+	events, err := withSpinner("Loading recent logs...", func() ([]logEvent, error) {
+		return recentLogEvents(logStream, containerLogTail)
+	})
+	if err != nil {
+		log.Printf("%s No log preview: %v", warnIcon(), err)
+		return
+	}
+
+	statusf("%s Last %d log event(s) of %s:\n", infoIcon(), len(events), containerName)
+	for _, event := range events {
+		timestamp := time.UnixMilli(event.Timestamp).Local().Format(time.DateTime)
+		fmt.Printf("   %s %s\n", timestamp, strings.TrimRight(event.Message, "\n"))
+	}
+}
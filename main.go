@@ -1,389 +1,1820 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/spf13/cobra"
 )
 
-const defaultRegionFile = "default_region.txt"
+const (
+	defaultRegionFile = "default_region.txt"
 
-var region string
+	// reconnectBaseDelay is the wait before the first --reconnect attempt, doubling for each one after it
+	reconnectBaseDelay = 2 * time.Second
+	// reconnectMaxDelay caps the wait between --reconnect attempts
+	reconnectMaxDelay = 30 * time.Second
+	// sessionWaitDelay is how long a cancelled session gets to exit before it's killed and its output pipes are closed
+	sessionWaitDelay = 5 * time.Second
+
+	// largeListThreshold is the number of listed resources above which we warn about menu size
+	largeListThreshold = 100
+
+	defaultPageSize = 20
+)
+
+var (
+	region    string
+	dualStack bool
+	noSave    bool
+	profile   string
+	noEmoji   bool
+	quiet     bool
+	viewLogs  bool
+	pageSize  int
+
+	maxResults int
+
+	reconnectAttempts int
+
+	sessionTimeout  time.Duration
+	execWaitTimeout time.Duration
+	promptTimeout   time.Duration
+
+	clusterFilter string
+	serviceFilter string
+	dryRun        bool
+	assumeYes     bool
+
+	clusterFlag        string
+	serviceFlag        string
+	taskFlag           string
+	searchTerm         string
+	containerFlag      string
+	primaryOnly        bool
+	commandShellDetect bool
+	interactiveSession bool
+	newWindow          bool
+	commandFile        string
+	commandFlag        string
+	commandArgs        []string
+	afterSession       string
+
+	extraSessionArgs []string
+)
+
+// managedSessionFlags are the execute-command flags runAWSSession always sets itself
+var managedSessionFlags = map[string]bool{
+	"--cluster":     true,
+	"--task":        true,
+	"--container":   true,
+	"--interactive": true,
+	"--command":     true,
+	"--region":      true,
+	"--profile":     true,
+}
 
 func main() {
+	// Let the root pre-run load the config file even for subcommands with their own hooks
+	cobra.EnableTraverseRunHooks = true
+
 	var rootCmd = &cobra.Command{
 		Use:   "ecs-session",
-		Short: "🚀 Interactive CLI tool for ECS Fargate task sessions",
+		Short: "🚀 Interactive CLI tool for ECS task sessions on Fargate, EC2 and ECS Anywhere",
+		// main prints errors itself, and usage only helps with flag mistakes
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		// Flags win over ECS_SESSION_ variables, then the config file, then the flag defaults
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := applyEnvDefaults(cmd); err != nil {
+				fatal(exitConfigError, "%s %v", errorIcon(), err)
+			}
+			stdio.timeout = promptTimeout
+			// Use the --theme right away so even config file warnings are drawn with it
+			if err := selectTheme(themeName, theme{}); err != nil {
+				fatal(exitConfigError, "%s %v", errorIcon(), err)
+			}
+			// Dropping an invalid config would also drop its safety settings, like denied_commands
+			settings, err := loadConfig()
+			if err != nil {
+				fatal(exitConfigError, "%s %v", errorIcon(), err)
+			}
+			fileConfig = settings
+			if !noSave {
+				migrateLegacyRegionFile(settings)
+			}
+			applyConfigDefaults(cmd, settings)
+			if err := selectTheme(themeName, settings.ThemeOverrides); err != nil {
+				fatal(exitConfigError, "%s %v", errorIcon(), err)
+			}
+		},
+		// Arguments after "--" are forwarded to aws ecs execute-command
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cmd.ArgsLenAtDash() != 0 && len(args) > 0 {
+				return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+			}
+			return nil
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			extraSessionArgs = filterExtraSessionArgs(args)
+			if !cmd.Flags().Changed("profile") && (chooseProfile || chooseProfileAlways && activeProfile() == "") {
+				profiles, err := listAWSProfiles()
+				if err != nil {
+					fatal(exitConfigError, "%s Unable to read the AWS profiles: %v", errorIcon(), err)
+				}
+				if len(profiles) == 0 {
+					fatal(exitConfigError, "%s No profiles found in the AWS config and credentials files", errorIcon())
+				}
+				profile = chooseOption("profile", profiles)
+			}
 			startSession()
 		},
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&region, "region", "r", "", "🌍 AWS Region (e.g., us-west-2)")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "p", "", "👤 AWS named profile (defaults to $AWS_PROFILE)")
+	rootCmd.PersistentFlags().BoolVar(&noSave, "no-save-region", false, "Don't offer the saved default region or to save one, and leave the region files alone")
+	rootCmd.PersistentFlags().BoolVar(&dualStack, "dualstack", false, "Use dual-stack (IPv4 and IPv6) AWS endpoints, also enabled by AWS_USE_DUALSTACK_ENDPOINT=true")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "no-emoji", false, "Replace emoji with plain ASCII markers (for CI logs)")
+	rootCmd.PersistentFlags().BoolVar(&noEmoji, "ascii", false, "Alias for --no-emoji")
+	rootCmd.PersistentFlags().StringVar(&themeName, "theme", "default", "🎨 Look of the markers, prompt and menu colours: "+strings.Join(themeNames(), ", "))
+	rootCmd.PersistentFlags().StringVar(&metricsFile, "metrics-file", "", "Write how long AWS calls and sessions took to this file in the Prometheus text format")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only show menus, prompts, warnings and errors")
+	rootCmd.PersistentFlags().DurationVar(&promptTimeout, "prompt-timeout", 0, "Exit when a prompt goes unanswered this long, e.g. 10m (0 waits forever)")
+	rootCmd.PersistentFlags().IntVar(&maxResults, "max-results", 0, "Stop listing clusters, services or tasks after this many, for speed (0 lists everything)")
+	rootCmd.PersistentFlags().IntVar(&pageSize, "page-size", defaultPageSize, "Number of items shown per menu page (0 shows everything)")
+	rootCmd.Flags().BoolVar(&chooseProfile, "choose-profile", false, "👤 Pick the AWS profile from the ones in ~/.aws/config and ~/.aws/credentials")
+	rootCmd.Flags().DurationVar(&sessionTimeout, "session-timeout", 0, "⏱️  End the session after this duration (e.g. 30m, default no timeout)")
+	rootCmd.Flags().DurationVar(&execWaitTimeout, "exec-wait-timeout", 5*time.Minute, "How long to wait for a task with execute-command enabled when a service has none yet (0 disables waiting)")
+	rootCmd.Flags().StringVar(&clusterFilter, "cluster-filter", "", "Only show clusters whose name matches this regex")
+	rootCmd.Flags().StringVar(&serviceFilter, "service-filter", "", "Only show services whose name matches this regex")
+	rootCmd.Flags().StringSliceVar(&pinnedClusters, "cluster-order", nil, "📌 Comma-separated clusters to pin to the top of the cluster menu, in this order")
+	rootCmd.Flags().StringVar(&clusterFlag, "cluster", "", "Cluster to use, by name or ARN (skips the cluster menu)")
+	rootCmd.Flags().StringVar(&serviceFlag, "service", "", "Service to use, by name or ARN (needs --cluster)")
+	rootCmd.Flags().BoolVar(&anyTask, "any-task", false, "Connect to any healthy task of --service running the container named exactly --container, skipping the task menu")
+	rootCmd.Flags().StringVar(&taskFlag, "task", "", "Task to use, by ID or ARN (needs --cluster)")
+	rootCmd.Flags().BoolVar(&fleetMode, "fleet", false, "🚢 Pick several clusters and run the command in the --service of that name in each")
+	rootCmd.Flags().StringArrayVar(&tagFlags, "tag", nil, "🏷️ Only show clusters and services tagged KEY=VALUE (repeatable, all must match)")
+	rootCmd.Flags().BoolVar(&allClusters, "all-clusters", false, "Like --fleet, but in every cluster matching --cluster-filter")
+	rootCmd.Flags().BoolVar(&stoppedTasks, "stopped", false, "🪦 Browse the recently stopped tasks of a service and why they stopped, without connecting")
+	rootCmd.Flags().StringVar(&searchTerm, "search", "", "🔎 Search the tasks of every cluster and service for this text and pick from the matches")
+	rootCmd.Flags().StringVar(&containerFlag, "container", "", "Container to connect to, by name (an unambiguous prefix is enough) or position in the task definition")
+	rootCmd.Flags().BoolVar(&primaryOnly, "primary-only", false, "Only list tasks from the service's PRIMARY deployment")
+	rootCmd.Flags().BoolVar(&commandShellDetect, "command-shell-detect", false, "Detect whether bash, ash or sh exists in the container and use it")
+	rootCmd.Flags().StringVar(&commandFlag, "command", "", "Command to run in the container, skipping the command menu (e.g. bash)")
+	rootCmd.Flags().StringArrayVar(&commandArgs, "arg", nil, "Command to run as separate arguments, quoted for you (repeat it: --arg sh --arg -c --arg 'echo hi && ls')")
+	rootCmd.Flags().StringVar(&commandFile, "command-from-file", "", "📄 Run the contents of this local script in the container")
+	rootCmd.Flags().BoolVar(&interactiveSession, "interactive", true, "Attach the terminal to the session; with --interactive=false the command's output is printed and ecs-session exits")
+	rootCmd.Flags().StringVar(&afterSession, "after-session", "exit", "What to do when the session ends: exit, menu (pick another container) or region (start over)")
+	rootCmd.Flags().BoolVar(&newWindow, "new-window", false, "🪟 Open the session in a new terminal window and keep this one free")
+	rootCmd.Flags().StringVar(&menuSort, "sort", "", "Order of the service and task menus: name, age (newest first) or status (default services by name, tasks oldest first)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Connect without showing the summary of what the session connects to")
+	rootCmd.Flags().IntVar(&reconnectAttempts, "reconnect", 0, "🔁 Start a dropped interactive session again, up to this many times with backoff")
+	rootCmd.Flags().BoolVar(&nativeSession, "native", false, "🧪 Experimental: start sessions through the SDK and session-manager-plugin, without the aws CLI")
+	rootCmd.Flags().StringVar(&emitSelectionPath, "emit-selection", "", "Write the selected cluster, service, task and containers as JSON to this file or FIFO before connecting")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the aws ecs execute-command command line instead of running it")
+	rootCmd.Flags().IntVar(&containerLogTail, "container-log-tail", 0, "Print the container's last N CloudWatch log events before connecting")
+	rootCmd.Flags().BoolVar(&viewLogs, "logs", false, "📜 Tail the selected container's CloudWatch logs instead of starting a session")
+	rootCmd.AddCommand(newListCommand(), newConfigCommand(), newDoctorCommand())
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		exitWith(exitCodeFor(err))
 	}
+	writeMetrics()
+}
+
+// sessionStep is a level of the selection flow
+type sessionStep int
+
+const (
+	stepCluster sessionStep = iota
+	stepService
+	stepTask
+	stepContainer
+	stepCommand
+)
+
+// selection holds what has been picked at each level so far
+type selection struct {
+	Account    string
+	Region     string
+	Cluster    string
+	Service    string
+	Task       string
+	Containers []string
+
+	// TaskSets limits the task menu to these task sets of a blue/green service
+	TaskSets []string
+
+	// AllContainers runs the command in every container of the task, without a terminal
+	AllContainers bool
+
+	// ExecDisabled is set when browsing a service without execute-command, whose tasks can be
+	// looked at but not connected to
+	ExecDisabled bool
+}
+
+// jumpKeys maps the letters shown in the breadcrumb to the level they jump back to
+var jumpKeys = map[string]sessionStep{
+	"c": stepCluster,
+	"s": stepService,
+	"t": stepTask,
 }
 
 func startSession() {
-	// Check if a default region is stored in the local file
-	if region == "" {
-		region = loadDefaultRegion()
-		if region != "" {
-			fmt.Printf("ℹ️  Found saved region '%s'. Do you want to use it? (y/n): ", region)
-			var useSaved string
-			fmt.Scanf("%s", &useSaved)
-			if strings.ToLower(useSaved) != "y" {
-				region = ""
-			}
+	clusterPattern, err := compileFilter("--cluster-filter", clusterFilter)
+	if err != nil {
+		fatal(exitConfigError, "%s %v", errorIcon(), err)
+	}
+	servicePattern, err := compileFilter("--service-filter", serviceFilter)
+	if err != nil {
+		fatal(exitConfigError, "%s %v", errorIcon(), err)
+	}
+	requiredTags, err = parseTagFilters(tagFlags)
+	if err != nil {
+		fatal(exitConfigError, "%s %v", errorIcon(), err)
+	}
+	if commandFlag != "" && commandFile != "" {
+		fatal(exitConfigError, "%s --command and --command-from-file can't be used together", errorIcon())
+	}
+	if len(commandArgs) > 0 {
+		if commandFlag != "" || commandFile != "" {
+			fatal(exitConfigError, "%s --arg can't be combined with --command or --command-from-file", errorIcon())
 		}
+		// execute-command takes one string that the agent splits again, so quote each argument
+		commandFlag = shellJoin(commandArgs)
+	}
+	if (fleetMode || allClusters) && clusterFlag != "" {
+		fatal(exitConfigError, "%s --cluster can't be combined with --fleet or --all-clusters", errorIcon())
 	}
+	if stoppedTasks && (taskFlag != "" || fleetMode || allClusters) {
+		fatal(exitConfigError, "%s --stopped can't be combined with --task, --fleet or --all-clusters", errorIcon())
+	}
+	if containerLogTail < 0 {
+		fatal(exitConfigError, "%s Invalid --container-log-tail %d: must not be negative", errorIcon(), containerLogTail)
+	}
+	if anyTask && (serviceFlag == "" || containerFlag == "" || taskFlag != "") {
+		fatal(exitConfigError, "%s --any-task needs --service and --container, and can't be combined with --task", errorIcon())
+	}
+	ensureSessionBackend()
+	if !validSortOrders[menuSort] {
+		fatal(exitConfigError, "%s Invalid --sort %q: must be name, age or status", errorIcon(), menuSort)
+	}
+	if afterSession != "exit" && afterSession != "menu" && afterSession != "region" {
+		fatal(exitConfigError, "%s Invalid --after-session %q: must be exit, menu or region", errorIcon(), afterSession)
+	}
+
+	settings := fileConfig
 
-	if region == "" {
-		region = enterOrChooseRegion()
-		saveRegionAsDefault(region)
+	// Read the script up front so a bad path fails before any navigation
+	var scriptCommand string
+	if commandFile != "" {
+		scriptCommand, err = readCommandFile(commandFile)
+		if err != nil {
+			fatal(exitConfigError, "%s %v", errorIcon(), err)
+		}
 	}
 
-	clearScreen()
-	fmt.Printf("✅ Region: %s\n", region)
+	sessionRegion := resolveRegion(settings)
+	clients, err := clientsFor(sessionRegion)
+	if err != nil {
+		fatal(exitConfigError, "%s Unable to load SDK config: %v", errorIcon(), err)
+	}
+	cfg := clients.Config
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	// Check the credentials now rather than failing on the first ECS call
+	identity, err := withSpinner("Checking credentials...", func() (*sts.GetCallerIdentityOutput, error) {
+		return callerIdentity(cfg)
+	})
 	if err != nil {
-		log.Fatalf("❌ Unable to load SDK config: %v", err)
+		fatal(exitAuthError, "%s Unable to verify your AWS credentials: %v", errorIcon(), err)
 	}
+	sessionCredentials = cfg.Credentials
 
-	ecsClient := ecs.NewFromConfig(cfg)
+	ecsClient := clients.ECS
+	ec2Client := clients.EC2
 
-	for {
-		clusterArns, err := listClusters(ecsClient)
+	// From here on the region travels with the selection, the --region global is only the starting point
+	sel := selection{Region: sessionRegion, Account: accountLabel(cfg, aws.ToString(identity.Account))}
+	if fleetMode || allClusters {
+		runFleet(ecsClient, sel, settings, scriptCommand, clusterPattern)
+		return
+	}
+	step := preselect(ecsClient, &sel)
+	if searchTerm != "" && step == stepCluster {
+		step = chooseSearchMatch(ecsClient, &sel, searchTerm)
+	}
+	refresh := false
+
+	// switchRegion asks for another region and starts over at its cluster menu
+	switchRegion := func() {
+		sessionRegion = enterOrChooseRegion()
+		clients, err := clientsFor(sessionRegion)
 		if err != nil {
-			log.Fatalf("❌ Unable to list clusters: %v", err)
+			fatal(exitConfigError, "%s Unable to load SDK config: %v", errorIcon(), err)
 		}
+		ecsClient = clients.ECS
+		ec2Client = clients.EC2
+		sessionCredentials = clients.Config.Credentials
+		sel = selection{Region: sessionRegion, Account: sel.Account}
+		step = stepCluster
+	}
 
-		clusterName := chooseOptionWithBack("cluster", clusterArns)
-		if clusterName == "BACK" {
-			region = ""
-			break
+	// lastCommand is the command of the session that just ended, repeatCommand is set when the
+	// user wants to run it again in the same containers
+	var lastCommand, repeatCommand string
+
+	// finishSession applies --after-session once a session or log tail has ended and reports whether to exit
+	finishSession := func() bool {
+		if afterSession == "exit" {
+			return true
 		}
-		clearScreen()
-		fmt.Printf("✅ Region: %s\n", region)
-		fmt.Printf("✅ Cluster: %s\n", clusterName)
 
-		for {
-			serviceArns, err := listServices(ecsClient, clusterName)
-			if err != nil {
-				log.Fatalf("❌ Unable to list services: %v", err)
+		// Going back to the menu offers to run the same command again, Enter being yes
+		if afterSession == "menu" && lastCommand != "" {
+			if confirmDefaultYes(fmt.Sprintf("%s Session ended. Run %s again? (Y/n): ", promptIcon(), commandDescription(lastCommand))) {
+				repeatCommand = lastCommand
+				step = stepCommand
+				return false
 			}
+			step = stepContainer
+			return false
+		}
+
+		waitForEnter(fmt.Sprintf("%s Session ended, press Enter to continue: ", promptIcon()))
+
+		switch afterSession {
+		case "menu":
+			step = stepContainer
+		case "region":
+			switchRegion()
+		}
+		return false
+	}
+
+	// Keep the identity on screen until the first menu has been answered
+	clearScreen()
+	statusf("%s Signed in to account %s as %s\n", okIcon(), aws.ToString(identity.Account), aws.ToString(identity.Arn))
+	firstScreen := true
+
+	for {
+		if !firstScreen {
+			clearScreen()
+		}
+		firstScreen = false
+		printBreadcrumb(sel, step)
 
-			serviceName := chooseOptionWithBack("service", serviceArns)
-			if serviceName == "BACK" {
-				break
+		switch step {
+		case stepCluster:
+			clusterNames, err := cachedList(listCacheKey("clusters", sel.Region), refresh, func() ([]string, error) {
+				return withSpinner("Loading clusters...", func() ([]string, error) {
+					names, err := listClusters(ecsClient)
+					if err != nil {
+						return nil, err
+					}
+					return filterClustersByTags(ecsClient, names)
+				})
+			})
+			if err != nil && !errors.Is(err, errNoClusters) {
+				fatal(awsExitCode(err), "%s Unable to list clusters: %v", errorIcon(), err)
+			}
+			clusterNames = filterNames(clusterNames, clusterPattern)
+			if len(clusterNames) == 0 {
+				fatal(exitNoResources, "%s No clusters found in region %s%s", errorIcon(), sel.Region, tagFilterNote())
 			}
 
-			// Check if the selected service has execute-command enabled
-			describeOutput, err := ecsClient.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
-				Cluster:  &clusterName,
-				Services: []string{serviceName},
+			clusterNames, pinned := pinNames(clusterNames, pinnedClusters)
+			choice := choosePinnedOptionWithBack("cluster", clusterNames, pinned)
+			refresh = choice.Action == menuRefresh
+			if refresh {
+				continue
+			}
+			// Going back from the clusters picks another region, Ctrl+D at the region prompt exits
+			if choice.Action == menuBack {
+				switchRegion()
+				continue
+			}
+			if target, ok := jumpTarget(choice, step); ok {
+				step = target
+				continue
+			}
+			sel.Cluster = choice.Value()
+			step = stepService
+
+		case stepService:
+			serviceNames, err := cachedList(listCacheKey("services", sel.Region, sel.Cluster), refresh, func() ([]string, error) {
+				return withSpinner("Loading services...", func() ([]string, error) {
+					names, err := listServices(ecsClient, sel.Cluster)
+					if err != nil {
+						return nil, err
+					}
+					if names, err = filterServicesByTags(ecsClient, sel.Cluster, names); err != nil {
+						return nil, err
+					}
+					return sortServicesForMenu(ecsClient, sel.Cluster, names)
+				})
 			})
 			if err != nil {
-				log.Fatalf("❌ Unable to describe services: %v", err)
+				fatal(awsExitCode(err), "%s Unable to list services: %v", errorIcon(), err)
+			}
+			serviceNames = filterNames(serviceNames, servicePattern)
+
+			choice := chooseOptionWithBack("service", serviceNames)
+			refresh = choice.Action == menuRefresh
+			if refresh {
+				continue
+			}
+			if choice.Action == menuBack {
+				step = stepCluster
+				continue
 			}
+			if target, ok := jumpTarget(choice, step); ok {
+				step = target
+				continue
+			}
+
+			serviceName := choice.Value()
 
-			service := describeOutput.Services[0]
-			if !service.EnableExecuteCommand {
+			// Check if the selected service has execute-command enabled
+			service, err := describeService(ecsClient, sel.Cluster, serviceName)
+			if err != nil {
+				fatal(awsExitCode(err), "%s Unable to describe services: %v", errorIcon(), err)
+			}
+			sel.ExecDisabled = !service.EnableExecuteCommand
+			if stoppedTasks {
+				// Stopped tasks are only looked at, so exec doesn't matter
+			} else if sel.ExecDisabled {
 				clearScreen()
-				fmt.Printf("⚠️  Execute-command is disabled for service: %s\n", serviceName)
-				fmt.Println("Do you want to go back and choose a different service? (y/n): ")
-				var goBack string
-				fmt.Scanf("%s", &goBack)
-				if strings.ToLower(goBack) == "y" {
+				promptf("%s Execute-command is disabled for service: %s\n", warnIcon(), serviceName)
+				promptf("   You can browse its tasks and containers and view their logs, but not connect to them.\n")
+				if !confirm(fmt.Sprintf("%s Browse its tasks anyway? (y/n): ", promptIcon())) {
 					continue
 				}
+			} else {
+				// When exec was only just enabled, the running tasks predate it
+				waited, err := waitForExecReadyTasks(ecsClient, sel.Cluster, service, execWaitTimeout)
+				if err != nil {
+					fatal(awsExitCode(err), "%s Unable to list tasks: %v", errorIcon(), err)
+				}
+				refresh = waited
 			}
 
-			clearScreen()
-			fmt.Printf("✅ Cluster: %s\n", clusterName)
-			fmt.Printf("✅ Service: %s\n", serviceName)
+			taskSets, next := chooseTaskSets(service)
+			if next != stepTask {
+				step = next
+				continue
+			}
+			sel.TaskSets = taskSets
+			sel.Service = serviceName
+			step = stepTask
 
-			for {
-				taskArns, err := listTasks(ecsClient, clusterName, serviceName)
+		case stepTask:
+			if stoppedTasks {
+				step = browseStoppedTasks(ecsClient, &sel, step)
+				continue
+			}
+			tasksKey := listCacheKey("tasks", sel.Region, sel.Cluster, sel.Service, strings.Join(sel.TaskSets, ","))
+			entries, err := cachedList(tasksKey, refresh, func() ([]taskMenuEntry, error) {
+				return withSpinner("Loading tasks...", func() ([]taskMenuEntry, error) {
+					return listTaskMenuEntries(ecsClient, ec2Client, sel.Cluster, sel.Service, sel.TaskSets)
+				})
+			})
+			if err != nil {
+				fatal(awsExitCode(err), "%s Unable to list tasks: %v", errorIcon(), err)
+			}
+			taskArns := make([]string, len(entries))
+			labelsByArn := make(map[string]string, len(entries))
+			for i, entry := range entries {
+				taskArns[i] = entry.Arn
+				labelsByArn[entry.Arn] = entry.Label
+			}
+			if primaryOnly {
+				taskArns, err = filterPrimaryDeploymentTasks(ecsClient, sel.Cluster, sel.Service, taskArns)
 				if err != nil {
-					log.Fatalf("❌ Unable to list tasks: %v", err)
+					fatal(awsExitCode(err), "%s Unable to filter tasks by deployment: %v", errorIcon(), err)
 				}
+			}
 
-				taskArn := chooseOptionWithBack("task", taskArns)
-				if taskArn == "BACK" {
-					break
+			taskLabels := make([]string, len(taskArns))
+			for i, taskArn := range taskArns {
+				taskLabels[i] = labelsByArn[taskArn]
+			}
+
+			choice := chooseLabeledOptionWithBack("task", taskArns, taskLabels, -1)
+			refresh = choice.Action == menuRefresh
+			if refresh {
+				continue
+			}
+			if choice.Action == menuBack {
+				step = stepService
+				continue
+			}
+			if target, ok := jumpTarget(choice, step); ok {
+				step = target
+				continue
+			}
+			sel.Task = choice.Value()
+			step = stepContainer
+
+		case stepContainer:
+			containerNames, unavailable, err := listContainers(ecsClient, sel.Cluster, sel.Task)
+			if err != nil {
+				fatal(awsExitCode(err), "%s Unable to list containers: %v", errorIcon(), err)
+			}
+			for _, container := range unavailable {
+				fmt.Printf("%s Container %s is unavailable\n", warnIcon(), container)
+			}
+			if len(containerNames) == 0 {
+				fatal(exitNoResources, "%s Task %s has no running container to connect to", errorIcon(), sel.Task)
+			}
+
+			// DescribeTasks has no stable container order, so follow the task definition
+			definedOrder, essential, err := containerDefinitions(ecsClient, sel.Cluster, sel.Task)
+			if err != nil {
+				log.Printf("%s Could not read the task definition: %v", warnIcon(), err)
+			}
+			sortByDefinedOrder(containerNames, definedOrder)
+
+			if containerFlag != "" {
+				matches := matchContainers(containerFlag, containerNames, definedOrder)
+				if len(matches) == 1 {
+					sel.Containers = matches[:1]
+					sel.AllContainers = false
+					step = stepCommand
+					continue
 				}
-				clearScreen()
-				fmt.Printf("✅ Cluster: %s\n", clusterName)
-				fmt.Printf("✅ Service: %s\n", serviceName)
-				fmt.Printf("✅ Task: %s\n", taskArn)
+				if len(matches) > 1 {
+					fmt.Printf("%s --container %s is ambiguous, it matches: %s\n", warnIcon(), containerFlag, strings.Join(matches, ", "))
+					containerNames = matches
+				} else {
+					fmt.Printf("%s No container matches --container %s\n", warnIcon(), containerFlag)
+				}
+			}
 
-				for {
-					containerNames, err := listContainers(ecsClient, clusterName, taskArn)
-					if err != nil {
-						log.Fatalf("❌ Unable to list containers: %v", err)
+			// Flag the essential container and preselect it
+			containerLabels := make([]string, len(containerNames))
+			defaultContainer := -1
+			for i, name := range containerNames {
+				containerLabels[i] = name
+				if essential[name] {
+					containerLabels[i] = name + " (essential)"
+					if defaultContainer < 0 {
+						defaultContainer = i
 					}
+				}
+			}
 
-					containerName := chooseOptionWithBack("container", containerNames)
-					if containerName == "BACK" {
-						break
-					}
-					clearScreen()
-					fmt.Printf("✅ Cluster: %s\n", clusterName)
-					fmt.Printf("✅ Service: %s\n", serviceName)
-					fmt.Printf("✅ Task: %s\n", taskArn)
-					fmt.Printf("✅ Container: %s\n", containerName)
-
-					command := chooseCommand()
-					clearScreen()
-					fmt.Printf("✅ Cluster: %s\n", clusterName)
-					fmt.Printf("✅ Service: %s\n", serviceName)
-					fmt.Printf("✅ Task: %s\n", taskArn)
-					fmt.Printf("✅ Container: %s\n", containerName)
-					runAWSSession(clusterName, taskArn, containerName, command)
-
-					// Session complete, exit or go back
+			options := containerNames
+			if len(containerNames) > 1 {
+				options = append(slices.Clip(containerNames), allContainers)
+				containerLabels = append(containerLabels, "All containers, running a command in each without a terminal")
+			}
+
+			// Several containers can be picked at once, e.g. "1,2"
+			choice := chooseLabeledOptionsWithBack("container", options, containerLabels, defaultContainer, true)
+			if choice.Action == menuRefresh {
+				continue
+			}
+			if choice.Action == menuBack {
+				step = stepTask
+				continue
+			}
+			if target, ok := jumpTarget(choice, step); ok {
+				step = target
+				continue
+			}
+			sel.Containers = choice.Values
+			sel.AllContainers = slices.Contains(choice.Values, allContainers)
+			if sel.AllContainers {
+				sel.Containers = containerNames
+			}
+			step = stepCommand
+
+		case stepCommand:
+			var command string
+			switch {
+			case repeatCommand != "":
+				command = repeatCommand
+				repeatCommand = ""
+			case viewLogs:
+				command = viewLogsCommand
+			case scriptCommand != "":
+				command = scriptCommand
+			case commandFlag != "":
+				command = commandFlag
+			case commandShellDetect:
+				command = detectShellCommand
+			case settings.DefaultCommand != "":
+				command = settings.DefaultCommand
+			default:
+				// A container_commands match only applies when connecting to that one container
+				containerName := ""
+				if len(sel.Containers) == 1 && !sel.AllContainers {
+					containerName = sel.Containers[0]
+				}
+				command = chooseCommand(preferredCommand(settings, sel.Cluster, containerName))
+			}
+			lastCommand = command
+			if command == viewLogsCommand {
+				container := sel.Containers[0]
+				if len(sel.Containers) > 1 {
+					fmt.Printf("%s Logs can be followed for one container at a time, showing %s\n", infoIcon(), container)
+				}
+				logStream, err := findContainerLogStream(ecsClient, sel.Region, sel.Cluster, sel.Task, container)
+				if err != nil {
+					fatal(awsExitCode(err), "%s Unable to find logs for container %s: %v", errorIcon(), container, err)
+				}
+				tailContainerLogs(logStream)
+				if finishSession() {
+					return
+				}
+				continue
+			}
+
+			clearScreen()
+			printBreadcrumb(sel, step)
+
+			// The task may have stopped while we were navigating the menus
+			task, err := validateTask(ecsClient, sel.Cluster, sel.Task)
+			if err != nil {
+				fatal(awsExitCode(err), "%s Cannot start session: %v", errorIcon(), err)
+			}
+
+			// Tasks started before exec was enabled on the service can't be exec'd into
+			if !task.EnableExecuteCommand && sel.ExecDisabled {
+				promptf("%s Can't connect: execute-command is disabled for service %s.\n", errorIcon(), sel.Service)
+				promptf("   Enable it and replace the tasks, then pick one of the new tasks:\n")
+				promptf("   aws ecs update-service --cluster %s --service %s --enable-execute-command --force-new-deployment\n", sel.Cluster, sel.Service)
+				waitForEnter(fmt.Sprintf("%s Press Enter to go back to the tasks: ", promptIcon()))
+				step = stepTask
+				continue
+			}
+			if !task.EnableExecuteCommand {
+				promptf("%s Task %s was started without execute-command enabled.\n", warnIcon(), sel.Task)
+				promptf("   Even if the service has it enabled now, exec only works in tasks started afterwards.\n")
+				if !confirm(fmt.Sprintf("%s Try anyway? (y/n): ", promptIcon())) {
+					step = stepTask
+					continue
+				}
+			}
+
+			// Tagged production resources need the cluster name typed out
+			if !dryRun && !confirmProtectedAccess(ecsClient, settings, sel.Cluster, sel.Service) {
+				fatal(exitUserAbort, "%s Session cancelled", infoIcon())
+			}
+
+			if sel.AllContainers {
+				if command == detectShellCommand || isShellCommand(command) {
+					fmt.Printf("%s A shell can't run in every container at once, pick a command like 'ps aux' instead\n", warnIcon())
+					step = stepContainer
+					continue
+				}
+				commands := make([]string, len(sel.Containers))
+				for i := range commands {
+					commands[i] = command
+				}
+				// Every container gets the command, so the summary lists them all before anything runs
+				if !dryRun && !confirmLaunch(sel, commands) {
+					containerFlag = ""
+					step = stepContainer
+					continue
+				}
+				emitSelection(sel, commands)
+				runInAllContainers(sel, command)
+				if finishSession() {
 					return
 				}
+				continue
+			}
+
+			commands := make([]string, len(sel.Containers))
+			for i := range commands {
+				commands[i] = command
+			}
+			if !dryRun && !confirmLaunch(sel, commands) {
+				// Show the container menu this time even if --container picked one
+				containerFlag = ""
+				step = stepContainer
+				continue
+			}
+
+			// Detection runs a command in the container, so it waits for the launch to be confirmed.
+			// Each container may have a different shell, so detect it per container.
+			for i, container := range sel.Containers {
+				if command != detectShellCommand {
+					break
+				}
+				if dryRun {
+					statusf("%s Skipping shell detection in dry-run mode, using sh\n", infoIcon())
+					commands[i] = "sh"
+				} else {
+					commands[i] = detectShell(sel.Region, sel.Cluster, sel.Task, container)
+				}
+			}
+			emitSelection(sel, commands)
+
+			var sessionErr error
+			if len(sel.Containers) == 1 {
+				if !dryRun && interactiveSession {
+					printLogPreview(ecsClient, sel.Region, sel.Cluster, sel.Task, sel.Containers[0])
+				}
+				sessionErr = runAWSSession(sel.Region, sel.Cluster, sel.Task, sel.Containers[0], commands[0])
+			} else {
+				sessionErr = runMultipleSessions(sel.Region, sel.Cluster, sel.Task, sel.Containers, commands)
+			}
+			// A deployment can replace the task between picking it and connecting
+			if errors.Is(sessionErr, errTaskGone) {
+				if !confirm(fmt.Sprintf("%s Task %s is no longer running. Pick another task of %s? (y/n): ", warnIcon(), sel.Task, sel.Service)) {
+					fatal(exitNoResources, "%s Task %s is gone", errorIcon(), sel.Task)
+				}
+				refresh = true
+				step = stepTask
+				continue
+			}
+
+			if finishSession() {
+				return
 			}
 		}
 	}
 }
 
-func enterOrChooseRegion() string {
-	fmt.Println("🔍 Would you like to:")
-	fmt.Println("1) Enter a region manually (e.g., us-west-2)")
-	fmt.Println("2) Choose from the 5 most-used regions")
+// commandDescription names a command for prompts, spelling out the menu's special choices
+func commandDescription(command string) string {
+	switch command {
+	case viewLogsCommand:
+		return "the log tail"
+	case detectShellCommand:
+		return "the detected shell"
+	default:
+		return fmt.Sprintf("'%s'", command)
+	}
+}
 
-	var choice int
-	fmt.Printf("➡️  Enter the number of your choice: ")
-	fmt.Scanf("%d", &choice)
+// resolveRegion picks the region for the session: --region, then the profile's region, then the
+// saved default if the user wants it, and otherwise asks
+func resolveRegion(settings *appConfig) string {
+	resolved := region
 
-	if choice == 1 {
-		var enteredRegion string
-		fmt.Printf("➡️  Enter your desired region code: ")
-		fmt.Scanf("%s", &enteredRegion)
-		return enteredRegion
-	} else {
-		return chooseRegion()
+	// A cluster ARN says which region the cluster lives in
+	if resolved == "" {
+		resolved = arnRegion(clusterFlag)
+	}
+
+	// A named profile with a configured region saves us from asking
+	if resolved == "" {
+		resolved = profileRegion()
+	}
+
+	// Check if a default region is stored in the local file
+	if resolved == "" {
+		resolved = loadDefaultRegion(settings)
+		if resolved != "" {
+			if !confirm(fmt.Sprintf("%s Found saved region '%s'. Do you want to use it? (y/n): ", infoIcon(), resolved)) {
+				resolved = ""
+			}
+		}
 	}
+
+	if resolved == "" {
+		resolved = enterOrChooseRegion()
+		if !noSave {
+			saveRegionAsDefault(resolved)
+		}
+	}
+	return resolved
 }
 
-func chooseRegion() string {
-	// Limiting to the 5 most-used regions
-	topRegions := []string{
-		"us-east-1",
-		"us-west-2",
-		"eu-west-1",
-		"ap-southeast-1",
-		"ap-northeast-1",
+// preselect fills in the levels given with --cluster, --service and --task and returns the first
+// step still to be chosen. They only apply once, so going back shows the menus as usual.
+func preselect(client *ecs.Client, sel *selection) sessionStep {
+	if clusterFlag == "" {
+		if serviceFlag != "" || taskFlag != "" {
+			fatal(exitConfigError, "%s --service and --task need --cluster", errorIcon())
+		}
+		return stepCluster
+	}
+	sel.Cluster = resourceName(clusterFlag, "cluster")
+	step := stepService
+
+	if serviceFlag != "" {
+		sel.Service = resourceName(serviceFlag, "service")
+		step = stepTask
+	}
+
+	if taskFlag != "" {
+		sel.Task = taskFlag
+		if sel.Service == "" {
+			// Tasks started by a service are in the group service:<name>
+			tasks, err := describeTasks(client, sel.Cluster, []string{sel.Task})
+			if err != nil {
+				fatal(awsExitCode(err), "%s Unable to describe task %s: %v", errorIcon(), sel.Task, err)
+			}
+			if len(tasks) == 0 {
+				fatal(exitNoResources, "%s Task %s not found in cluster %s", errorIcon(), sel.Task, sel.Cluster)
+			}
+			sel.Service, _ = strings.CutPrefix(aws.ToString(tasks[0].Group), "service:")
+		}
+		step = stepContainer
 	}
 
-	return chooseOption("region", topRegions)
+	if anyTask {
+		taskArn, err := withSpinner(fmt.Sprintf("Finding a task of %s running %s...", sel.Service, containerFlag), func() (string, error) {
+			return pickAnyTask(client, sel.Cluster, sel.Service, containerFlag)
+		})
+		if err != nil {
+			fatal(awsExitCode(err), "%s Unable to list tasks: %v", errorIcon(), err)
+		}
+		if taskArn == "" {
+			fatal(exitNoResources, "%s No healthy running task of %s with execute-command enabled runs container %s", errorIcon(), sel.Service, containerFlag)
+		}
+		statusf("%s Using task %s\n", infoIcon(), taskArn)
+		sel.Task = taskArn
+		step = stepContainer
+	}
+	return step
 }
 
-func listClusters(client *ecs.Client) ([]string, error) {
-	output, err := client.ListClusters(context.TODO(), &ecs.ListClustersInput{})
+// printBreadcrumb shows the levels selected above the current step, with the keys to jump back to them
+func printBreadcrumb(sel selection, step sessionStep) {
+	if quiet {
+		return
+	}
+	crumbs := []string{"Region: " + sel.Region}
+	if step > stepCluster {
+		crumbs = append(crumbs, "Cluster [c]: "+sel.Cluster)
+	}
+	if step > stepService {
+		service := "Service [s]: " + sel.Service
+		if sel.ExecDisabled {
+			service += " (exec disabled)"
+		}
+		crumbs = append(crumbs, service)
+	}
+	if step > stepTask {
+		crumbs = append(crumbs, "Task [t]: "+sel.Task)
+	}
+	if step > stepContainer {
+		crumbs = append(crumbs, "Container: "+strings.Join(sel.Containers, ", "))
+	}
+
+	fmt.Printf("%s Account: %s | %s\n", okIcon(), sel.Account, strings.Join(crumbs, " > "))
+	if step > stepCluster && step < stepCommand {
+		fmt.Println("   (type a letter in [] to jump back to that level)")
+	}
+}
+
+// jumpTarget reports whether the menu choice is a breadcrumb jump to a level above the current step
+func jumpTarget(choice menuChoice, step sessionStep) (sessionStep, bool) {
+	if choice.Action != menuJump {
+		return step, false
+	}
+	target, ok := jumpKeys[choice.JumpKey]
+	if !ok || target >= step {
+		// Unknown or not yet reached, stay where we are
+		return step, true
+	}
+	return target, true
+}
+
+// activeProfile returns the profile selected with --profile or $AWS_PROFILE
+func activeProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("AWS_PROFILE")
+}
+
+// profileRegion returns the region configured for the active profile in the shared config, if any
+func profileRegion() string {
+	name := activeProfile()
+	if name == "" {
+		return ""
+	}
+	sharedConfig, err := config.LoadSharedConfigProfile(context.TODO(), name)
 	if err != nil {
-		return nil, err
+		return ""
 	}
+	return sharedConfig.Region
+}
 
-	return extractNamesFromArns(output.ClusterArns, "cluster"), nil
+// loadAWSConfig loads the SDK config for the region, using the --profile if one was given.
+// Without --profile the default credential chain is used untouched, so credentials injected
+// through the environment (e.g. by aws-vault exec) take precedence as usual.
+func loadAWSConfig(region string) (aws.Config, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+	// Without --dualstack the SDK still honours AWS_USE_DUALSTACK_ENDPOINT and use_dualstack_endpoint
+	if dualStack {
+		options = append(options, config.WithUseDualStackEndpoint(aws.DualStackEndpointStateEnabled))
+	}
+	// Without --metrics-file the SDK calls aren't wrapped at all
+	if metricsFile != "" {
+		options = append(options, config.WithAPIOptions(metricsAPIOptions()))
+	}
+	return config.LoadDefaultConfig(context.TODO(), options...)
+}
+
+// listClusters returns the names of the region's clusters, or an error wrapping errNoClusters when it has none
+func listClusters(client *ecs.Client) ([]string, error) {
+	var clusterArns []string
+	pages := 0
+	truncated := false
+	paginator := ecs.NewListClustersPaginator(client, &ecs.ListClustersInput{MaxResults: maxResultsPerPage()})
+	for paginator.HasMorePages() && !truncated {
+		pages++
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:ListClusters", "all clusters")
+		}
+		clusterArns, truncated = limitResults(append(clusterArns, output.ClusterArns...), paginator.HasMorePages())
+	}
+	if len(clusterArns) == 0 {
+		return nil, fmt.Errorf("%w in region %s", errNoClusters, client.Options().Region)
+	}
+
+	reportListed("clusters", len(clusterArns), pages, truncated)
+	names := extractNamesFromArns(clusterArns, "cluster")
+	sort.Strings(names)
+	return names, nil
 }
 
 func listServices(client *ecs.Client, clusterArn string) ([]string, error) {
-	output, err := client.ListServices(context.TODO(), &ecs.ListServicesInput{
-		Cluster: &clusterArn,
+	var serviceArns []string
+	pages := 0
+	truncated := false
+	paginator := ecs.NewListServicesPaginator(client, &ecs.ListServicesInput{
+		Cluster:    &clusterArn,
+		MaxResults: maxResultsPerPage(),
 	})
+	for paginator.HasMorePages() && !truncated {
+		pages++
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:ListServices", "cluster "+clusterArn)
+		}
+		serviceArns, truncated = limitResults(append(serviceArns, output.ServiceArns...), paginator.HasMorePages())
+	}
+
+	reportListed("services", len(serviceArns), pages, truncated)
+	names := extractNamesFromArns(serviceArns, "service")
+	sort.Strings(names)
+	return names, nil
+}
+
+func listTasks(client *ecs.Client, clusterArn string, serviceName string) ([]string, error) {
+	tasks, err := listServiceTasks(client, clusterArn, serviceName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	taskArns := make([]string, len(tasks))
+	for i, task := range tasks {
+		taskArns[i] = aws.ToString(task.TaskArn)
+	}
+	return taskArns, nil
+}
+
+// listServiceTasks returns the described tasks of a service, oldest first. With taskSetIDs only the
+// tasks of those task sets are returned, found through the task set ID they were started by.
+func listServiceTasks(client *ecs.Client, clusterArn string, serviceName string, taskSetIDs []string) ([]types.Task, error) {
+	inputs := []*ecs.ListTasksInput{serviceTasksInput(clusterArn, serviceName)}
+	if len(taskSetIDs) > 0 {
+		inputs = nil
+		for _, taskSetID := range taskSetIDs {
+			inputs = append(inputs, &ecs.ListTasksInput{Cluster: &clusterArn, StartedBy: aws.String(taskSetID), MaxResults: maxResultsPerPage()})
+		}
+	}
+
+	var taskArns []string
+	pages := 0
+	truncated := false
+	for i, input := range inputs {
+		paginator := ecs.NewListTasksPaginator(client, input)
+		for paginator.HasMorePages() && !truncated {
+			pages++
+			output, err := paginator.NextPage(context.TODO())
+			if err != nil {
+				return nil, wrapAWSError(err, "ecs:ListTasks", "cluster "+clusterArn)
+			}
+			taskArns, truncated = limitResults(append(taskArns, output.TaskArns...), paginator.HasMorePages() || i < len(inputs)-1)
+		}
+	}
+
+	reportListed("tasks", len(taskArns), pages, truncated)
+
+	// ListTasks has no stable order, so sort by start time to keep menu numbers predictable
+	tasks, err := describeTasks(client, clusterArn, taskArns)
+	if err != nil {
+		return nil, err
+	}
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+// taskMenuEntry is a task as shown in the task menu
+type taskMenuEntry struct {
+	Arn   string
+	Label string
+}
+
+// listTaskMenuEntries lists the service's tasks with labels describing where each one runs
+func listTaskMenuEntries(client *ecs.Client, ec2Client *ec2.Client, clusterArn string, serviceName string, taskSetIDs []string) ([]taskMenuEntry, error) {
+	tasks, err := listServiceTasks(client, clusterArn, serviceName, taskSetIDs)
 	if err != nil {
 		return nil, err
 	}
+	sortTasksForMenu(tasks)
+
+	// Tasks on EC2 and ECS Anywhere hosts are easier to recognise by their instance ID
+	instanceIDs, err := describeTaskHosts(client, clusterArn, tasks)
+	if err != nil {
+		log.Printf("%s Could not look up the container instances of the tasks: %v", warnIcon(), err)
+	}
+	publicIPs, err := describePublicIPs(ec2Client, tasks)
+	if err != nil {
+		log.Printf("%s Could not look up the public IPs of the tasks: %v", warnIcon(), err)
+	}
 
-	return extractNamesFromArns(output.ServiceArns, "service"), nil
+	entries := make([]taskMenuEntry, len(tasks))
+	for i, task := range tasks {
+		entries[i] = taskMenuEntry{
+			Arn:   aws.ToString(task.TaskArn),
+			Label: taskLabel(task, instanceIDs[aws.ToString(task.ContainerInstanceArn)], publicIPs[taskNetworkInterfaceID(task)]),
+		}
+	}
+	return entries, nil
 }
 
-func listTasks(client *ecs.Client, clusterArn string, serviceArn string) ([]string, error) {
-	output, err := client.ListTasks(context.TODO(), &ecs.ListTasksInput{
-		Cluster:     &clusterArn,
-		ServiceName: &serviceArn,
+// describeTaskHosts maps the container instance ARNs of the tasks to their instance IDs. ECS
+// Anywhere hosts have managed instance IDs (mi-...) there instead of EC2 instance IDs.
+func describeTaskHosts(client *ecs.Client, clusterArn string, tasks []types.Task) (map[string]string, error) {
+	const batchSize = 100
+
+	var instanceArns []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		instanceArn := aws.ToString(task.ContainerInstanceArn)
+		if instanceArn != "" && !seen[instanceArn] {
+			seen[instanceArn] = true
+			instanceArns = append(instanceArns, instanceArn)
+		}
+	}
+
+	instanceIDs := make(map[string]string)
+	for start := 0; start < len(instanceArns); start += batchSize {
+		end := min(start+batchSize, len(instanceArns))
+		output, err := client.DescribeContainerInstances(context.TODO(), &ecs.DescribeContainerInstancesInput{
+			Cluster:            &clusterArn,
+			ContainerInstances: instanceArns[start:end],
+		})
+		if err != nil {
+			return instanceIDs, wrapAWSError(err, "ecs:DescribeContainerInstances", "cluster "+clusterArn)
+		}
+		for _, instance := range output.ContainerInstances {
+			instanceIDs[aws.ToString(instance.ContainerInstanceArn)] = aws.ToString(instance.Ec2InstanceId)
+		}
+	}
+	return instanceIDs, nil
+}
+
+// taskPrivateIP returns the private IPv4 address of the task's network interface, if it has one
+func taskPrivateIP(task types.Task) string {
+	for _, attachment := range task.Attachments {
+		if aws.ToString(attachment.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, detail := range attachment.Details {
+			if aws.ToString(detail.Name) == "privateIPv4Address" {
+				return aws.ToString(detail.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// taskLabel shows the task definition with the task's platform version and CPU/memory, then the task ARN with its
+// private and public IPs, availability zone and host, so replicas of the same service can be told apart. Fields
+// the launch type doesn't have are left out.
+func taskLabel(task types.Task, instanceID string, publicIP string) string {
+	var details []string
+	if task.LaunchType == types.LaunchTypeExternal {
+		details = append(details, "ECS Anywhere")
+	}
+	if ip := taskPrivateIP(task); ip != "" {
+		details = append(details, ip)
+	}
+	if publicIP != "" {
+		details = append(details, "public "+publicIP)
+	}
+	if task.AvailabilityZone != nil {
+		details = append(details, aws.ToString(task.AvailabilityZone))
+	}
+	if instanceID != "" {
+		details = append(details, instanceID)
+	}
+
+	label := aws.ToString(task.TaskArn)
+	if len(details) > 0 {
+		label = fmt.Sprintf("%s (%s)", label, strings.Join(details, ", "))
+	}
+
+	// Lead with the task definition and its resources, e.g. "api:42 (1.4.0, 512/1024)"
+	var resources []string
+	if task.PlatformVersion != nil {
+		resources = append(resources, aws.ToString(task.PlatformVersion))
+	}
+	if task.Cpu != nil || task.Memory != nil {
+		resources = append(resources, fmt.Sprintf("%s/%s", aws.ToString(task.Cpu), aws.ToString(task.Memory)))
+	}
+	taskDefinition := aws.ToString(task.TaskDefinitionArn)
+	taskDefinition = taskDefinition[strings.LastIndex(taskDefinition, "/")+1:]
+	if len(resources) > 0 {
+		taskDefinition = fmt.Sprintf("%s (%s)", taskDefinition, strings.Join(resources, ", "))
+	}
+	if taskDefinition == "" {
+		return label
+	}
+	separator := " — "
+	if noEmoji {
+		separator = " - "
+	}
+	return taskDefinition + separator + label
+}
+
+// sortTasks orders tasks by start time, oldest first, then by ARN. Tasks that haven't started yet go last.
+func sortTasks(tasks []types.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.StartedAt == nil || b.StartedAt == nil {
+			if (a.StartedAt == nil) != (b.StartedAt == nil) {
+				return b.StartedAt == nil
+			}
+		} else if !a.StartedAt.Equal(*b.StartedAt) {
+			return a.StartedAt.Before(*b.StartedAt)
+		}
+		return aws.ToString(a.TaskArn) < aws.ToString(b.TaskArn)
 	})
+}
+
+// serviceTasksInput lists the tasks of a service. The service is always passed to ECS by its short
+// name, even when given as an ARN.
+func serviceTasksInput(clusterArn string, serviceName string) *ecs.ListTasksInput {
+	return &ecs.ListTasksInput{
+		Cluster:     &clusterArn,
+		ServiceName: aws.String(resourceName(serviceName, "service")),
+		MaxResults:  maxResultsPerPage(),
+	}
+}
+
+// describeServiceInput describes a single service by its short name, even when given as an ARN
+func describeServiceInput(clusterArn string, serviceName string) *ecs.DescribeServicesInput {
+	return &ecs.DescribeServicesInput{
+		Cluster:  &clusterArn,
+		Services: []string{resourceName(serviceName, "service")},
+	}
+}
+
+// describeService returns the details of a single service, given by name or ARN
+func describeService(client *ecs.Client, clusterArn string, serviceName string) (*types.Service, error) {
+	input := describeServiceInput(clusterArn, serviceName)
+	serviceName = input.Services[0]
+	output, err := client.DescribeServices(context.TODO(), input)
+	if err != nil {
+		return nil, wrapAWSError(err, "ecs:DescribeServices", "service "+serviceName+" in cluster "+clusterArn)
+	}
+	if len(output.Services) == 0 {
+		return nil, fmt.Errorf("service %s not found in cluster %s", serviceName, clusterArn)
+	}
+	return &output.Services[0], nil
+}
+
+// describeTasks describes any number of tasks, batching the calls to stay within the API limit
+func describeTasks(client *ecs.Client, clusterArn string, taskArns []string) ([]types.Task, error) {
+	const batchSize = 100
+
+	var tasks []types.Task
+	for start := 0; start < len(taskArns); start += batchSize {
+		end := min(start+batchSize, len(taskArns))
+		output, err := client.DescribeTasks(context.TODO(), &ecs.DescribeTasksInput{
+			Cluster: &clusterArn,
+			Tasks:   taskArns[start:end],
+		})
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:DescribeTasks", "tasks in cluster "+clusterArn)
+		}
+		// Tasks that stopped since they were listed come back as MISSING failures
+		for _, failure := range output.Failures {
+			log.Printf("%s Skipping %s: %s", warnIcon(), aws.ToString(failure.Arn), strings.ToLower(aws.ToString(failure.Reason)))
+		}
+		tasks = append(tasks, output.Tasks...)
+	}
+	return tasks, nil
+}
+
+// filterPrimaryDeploymentTasks keeps only the tasks started by the service's PRIMARY deployment
+func filterPrimaryDeploymentTasks(client *ecs.Client, clusterArn string, serviceName string, taskArns []string) ([]string, error) {
+	service, err := describeService(client, clusterArn, serviceName)
 	if err != nil {
 		return nil, err
 	}
 
-	return output.TaskArns, nil
+	var primaryID string
+	for _, deployment := range service.Deployments {
+		if aws.ToString(deployment.Status) == "PRIMARY" {
+			primaryID = aws.ToString(deployment.Id)
+			break
+		}
+	}
+	if primaryID == "" {
+		return nil, fmt.Errorf("service %s has no PRIMARY deployment", serviceName)
+	}
+
+	tasks, err := describeTasks(client, clusterArn, taskArns)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []string
+	for _, task := range tasks {
+		if aws.ToString(task.StartedBy) == primaryID {
+			filtered = append(filtered, aws.ToString(task.TaskArn))
+		}
+	}
+	return filtered, nil
+}
+
+// compileFilter compiles a name filter regex, returning nil when no filter was given
+func compileFilter(flagName string, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s regex %q: %v", flagName, pattern, err)
+	}
+	return compiled, nil
+}
+
+// filterNames keeps only the names matching the pattern; a nil pattern keeps everything
+func filterNames(names []string, pattern *regexp.Regexp) []string {
+	if pattern == nil {
+		return names
+	}
+	var filtered []string
+	for _, name := range names {
+		if pattern.MatchString(name) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// maxResultsPerPage asks for no more than --max-results items per page, up to the ECS limit of 100
+func maxResultsPerPage() *int32 {
+	if maxResults <= 0 {
+		return nil
+	}
+	return aws.Int32(int32(min(maxResults, 100)))
 }
 
-func listContainers(client *ecs.Client, clusterArn string, taskArn string) ([]string, error) {
+// limitResults cuts arns down to --max-results and reports whether that left items out, which is
+// assumed once the limit is reached while more pages remain
+func limitResults(arns []string, morePages bool) ([]string, bool) {
+	if maxResults <= 0 || len(arns) < maxResults {
+		return arns, false
+	}
+	truncated := len(arns) > maxResults || morePages
+	return arns[:maxResults], truncated
+}
+
+// reportListed tells the user how much a list call that took several pages fetched, and warns
+// when the menu is going to be very long
+func reportListed(entity string, count int, pages int, truncated bool) {
+	// This runs inside withSpinner, so print through it rather than over it
+	if (pages > 1 || truncated) && !quiet {
+		spinnerPrintf("%s Loaded %d %s (%d pages)\n", infoIcon(), count, entity, pages)
+	}
+	if truncated {
+		spinnerPrintf("%s Stopped after the first %d %s because of --max-results, the list is incomplete\n", warnIcon(), count, entity)
+	}
+	if count > largeListThreshold {
+		spinnerPrintf("%s Found %d %s, the menu will be long\n", warnIcon(), count, entity)
+	}
+}
+
+// listContainers returns the running containers of the task, which are the ones that can accept a
+// session, and descriptions of the others, e.g. "worker (STOPPED)"
+func listContainers(client *ecs.Client, clusterArn string, taskArn string) ([]string, []string, error) {
 	output, err := client.DescribeTasks(context.TODO(), &ecs.DescribeTasksInput{
 		Cluster: &clusterArn,
 		Tasks:   []string{taskArn},
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, wrapAWSError(err, "ecs:DescribeTasks", "task "+taskArn)
+	}
+	if len(output.Tasks) == 0 {
+		return nil, nil, describeTasksFailure(taskArn, output.Failures)
 	}
 
-	var containerNames []string
-	if len(output.Tasks) > 0 {
-		for _, container := range output.Tasks[0].Containers {
-			containerNames = append(containerNames, aws.ToString(container.Name))
+	var containerNames, unavailable []string
+	for _, container := range output.Tasks[0].Containers {
+		name := aws.ToString(container.Name)
+		if status := aws.ToString(container.LastStatus); status != "RUNNING" {
+			unavailable = append(unavailable, fmt.Sprintf("%s (%s)", name, status))
+			continue
 		}
+		containerNames = append(containerNames, name)
 	}
 
-	return containerNames, nil
+	return containerNames, unavailable, nil
+}
+
+// describeTasksFailure explains why DescribeTasks returned no task for taskArn
+func describeTasksFailure(taskArn string, failures []types.Failure) error {
+	reason := "not found"
+	if len(failures) > 0 {
+		reason = strings.ToLower(aws.ToString(failures[0].Reason))
+		if detail := aws.ToString(failures[0].Detail); detail != "" {
+			reason += ": " + detail
+		}
+	}
+	return fmt.Errorf("task %s: %s", taskArn, reason)
+}
+
+// matchContainers resolves a container name given on the command line. An exact match wins,
+// otherwise every container starting with the name is returned.
+func matchContainers(name string, containerNames []string, definedOrder []string) []string {
+	// A number is the container's position in the task definition, counting from 1
+	if position, err := strconv.Atoi(name); err == nil && position >= 1 && position <= len(definedOrder) {
+		name = definedOrder[position-1]
+		if !slices.Contains(containerNames, name) {
+			return nil
+		}
+		return []string{name}
+	}
+
+	var matches []string
+	for _, containerName := range containerNames {
+		if containerName == name {
+			return []string{containerName}
+		}
+		if strings.HasPrefix(containerName, name) {
+			matches = append(matches, containerName)
+		}
+	}
+	return matches
+}
+
+// describeTaskDefinition fetches the task definition the given task was started from
+func describeTaskDefinition(client *ecs.Client, clusterArn string, taskArn string) (*types.TaskDefinition, error) {
+	tasksOutput, err := client.DescribeTasks(context.TODO(), &ecs.DescribeTasksInput{
+		Cluster: &clusterArn,
+		Tasks:   []string{taskArn},
+	})
+	if err != nil {
+		return nil, wrapAWSError(err, "ecs:DescribeTasks", "task "+taskArn)
+	}
+	if len(tasksOutput.Tasks) == 0 {
+		return nil, fmt.Errorf("task %s not found in cluster %s", taskArn, clusterArn)
+	}
+
+	taskDefOutput, err := client.DescribeTaskDefinition(context.TODO(), &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: tasksOutput.Tasks[0].TaskDefinitionArn,
+	})
+	if err != nil {
+		return nil, wrapAWSError(err, "ecs:DescribeTaskDefinition", aws.ToString(tasksOutput.Tasks[0].TaskDefinitionArn))
+	}
+	return taskDefOutput.TaskDefinition, nil
+}
+
+// containerDefinitions returns the container names in task definition order and which of them are essential
+func containerDefinitions(client *ecs.Client, clusterArn string, taskArn string) ([]string, map[string]bool, error) {
+	taskDef, err := describeTaskDefinition(client, clusterArn, taskArn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var order []string
+	essential := make(map[string]bool)
+	for _, containerDef := range taskDef.ContainerDefinitions {
+		name := aws.ToString(containerDef.Name)
+		order = append(order, name)
+		// Containers are essential unless the task definition says otherwise
+		if containerDef.Essential == nil || *containerDef.Essential {
+			essential[name] = true
+		}
+	}
+	return order, essential, nil
+}
+
+// sortByDefinedOrder sorts container names by their position in the task definition. Names the
+// task definition doesn't know, such as ECS-managed sidecars, go last.
+func sortByDefinedOrder(containerNames []string, definedOrder []string) {
+	position := func(name string) int {
+		if i := slices.Index(definedOrder, name); i >= 0 {
+			return i
+		}
+		return len(definedOrder)
+	}
+	sort.SliceStable(containerNames, func(i, j int) bool {
+		return position(containerNames[i]) < position(containerNames[j])
+	})
+}
+
+// validateTask makes sure the task exists, is running and belongs to the given cluster
+func validateTask(client *ecs.Client, clusterName string, taskArn string) (*types.Task, error) {
+	output, err := client.DescribeTasks(context.TODO(), &ecs.DescribeTasksInput{
+		Cluster: &clusterName,
+		Tasks:   []string{taskArn},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe task %s: %w", taskArn, wrapAWSError(err, "ecs:DescribeTasks", "task "+taskArn))
+	}
+
+	if len(output.Tasks) == 0 {
+		reason := "not found"
+		if len(output.Failures) > 0 {
+			reason = strings.ToLower(aws.ToString(output.Failures[0].Reason))
+		}
+		return nil, fmt.Errorf("task %s in cluster %s: %s", taskArn, clusterName, reason)
+	}
+
+	task := output.Tasks[0]
+	taskCluster := extractNamesFromArns([]string{aws.ToString(task.ClusterArn)}, "cluster")
+	if len(taskCluster) == 0 || taskCluster[0] != clusterName {
+		return nil, fmt.Errorf("task %s does not belong to cluster %s", taskArn, clusterName)
+	}
+
+	if status := aws.ToString(task.LastStatus); status != "RUNNING" {
+		return nil, fmt.Errorf("task %s is %s, not RUNNING", taskArn, status)
+	}
+
+	return &task, nil
+}
+
+// resourceName accepts a name or a full ARN for a cluster or service and returns the name
+func resourceName(value string, resourceType string) string {
+	if !arn.IsARN(value) {
+		return value
+	}
+	return extractNamesFromArns([]string{value}, resourceType)[0]
+}
+
+// arnRegion returns the region part of an ARN, or "" when value isn't an ARN
+func arnRegion(value string) string {
+	parsed, err := arn.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return parsed.Region
 }
 
 func extractNamesFromArns(arns []string, resourceType string) []string {
 	var names []string
-	for _, arn := range arns {
-		parts := strings.Split(arn, ":")
-		if resourceType == "cluster" {
-			names = append(names, strings.Split(parts[5], "/")[1]) // Extracting the cluster name
-		} else if resourceType == "service" {
-			names = append(names, strings.Split(parts[5], "/")[2]) // Extracting the service name
-		} else {
-			names = append(names, arn) // For tasks, keep the ARN intact
-		}
+	for _, resourceArn := range arns {
+		names = append(names, arnResourceName(resourceArn, resourceType))
 	}
 	return names
 }
 
-func runAWSSession(clusterArn string, taskArn string, containerName string, command string) {
-	cmd := exec.Command("aws", "ecs", "execute-command",
+// arnResourceName returns the cluster or service name from an ECS ARN. The resource part is taken
+// whole, so colons and slashes in legacy cluster names survive. Task ARNs and anything that
+// doesn't parse are returned unchanged.
+func arnResourceName(resourceArn string, resourceType string) string {
+	parsed, err := arn.Parse(resourceArn)
+	if err != nil {
+		return resourceArn
+	}
+
+	switch resourceType {
+	case "cluster":
+		if name, ok := strings.CutPrefix(parsed.Resource, "cluster/"); ok {
+			return name
+		}
+	case "service":
+		// Long ARNs are service/<cluster>/<service>, older short ones are service/<service>.
+		// Service names never contain a slash, unlike some legacy cluster names.
+		if rest, ok := strings.CutPrefix(parsed.Resource, "service/"); ok {
+			return rest[strings.LastIndex(rest, "/")+1:]
+		}
+	}
+	return resourceArn
+}
+
+// execCommandArgs builds the aws CLI arguments for an execute-command session
+func execCommandArgs(region string, clusterArn string, taskArn string, containerName string, command string) []string {
+	args := []string{"ecs", "execute-command",
 		"--cluster", clusterArn,
 		"--task", taskArn,
 		"--container", containerName,
 		"--interactive",
 		"--command", command,
-		"--region", region)
+		"--region", region}
+	// Sessions get the profile and credentials through childEnv, where --profile would beat the
+	// credentials, so it's only added to the copy-pasteable dry-run command line
+	if profile != "" && dryRun {
+		args = append(args, "--profile", profile)
+	}
+	return append(args, extraSessionArgs...)
+}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+// runAWSSession runs the execute-command session. It exits on failures, except when the task has
+// gone away, which it returns as errTaskGone so another task can be picked.
+func runAWSSession(region string, clusterArn string, taskArn string, containerName string, command string) error {
+	err := tryAWSSession(region, clusterArn, taskArn, containerName, command)
+	if err != nil && !errors.Is(err, errTaskGone) {
+		fatal(exitCodeFor(err), "%s %v", errorIcon(), err)
+	}
+	return err
+}
+
+// tryAWSSession runs the execute-command session and returns why it failed, with the exit code
+// attached, for callers like --fleet that carry on with the next target
+func tryAWSSession(region string, clusterArn string, taskArn string, containerName string, command string) error {
+	// Without --session-timeout the session runs until the user exits it
+	ctx := context.Background()
+	if sessionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sessionTimeout)
+		defer cancel()
+	}
+
+	if err := checkDeniedCommand(command); err != nil {
+		return withExitCode(exitConfigError, fmt.Errorf("refusing to start the session: %w", err))
+	}
+
+	args := execCommandArgs(region, clusterArn, taskArn, containerName, command)
+
+	if dryRun {
+		fmt.Println(shellJoin(append([]string{"aws"}, args...)))
+		return nil
+	}
 
-	fmt.Println("🚀 Starting AWS CLI execute-command session...")
-	if err := cmd.Run(); err != nil {
-		log.Printf("❌ Failed to start execute-command session: %v", err)
+	if !nativeSession {
+		if err := checkAWSCLIVersion(); err != nil {
+			log.Printf("%s %v", warnIcon(), err)
+		}
+	}
+
+	if newWindow && interactiveSession && !nativeSession {
+		err := openInNewWindow(args)
+		if err == nil {
+			statusf("%s Session opened in a new terminal window\n", launchIcon())
+			return nil
+		}
+		log.Printf("%s Could not open a new terminal window, starting the session here: %v", warnIcon(), err)
+	}
+
+	// Keep a copy of stderr so we can explain common failures after the CLI exits
+	var stderr bytes.Buffer
+	err := runSessionProcess(ctx, region, clusterArn, taskArn, containerName, command, args, &stderr)
+	// Only interactive sessions are retried, a non-interactive command might not be safe to run twice
+	for attempt := 1; err != nil && attempt <= reconnectAttempts && interactiveSession && canReconnect(ctx, stderr.String()); attempt++ {
+		delay := min(reconnectBaseDelay<<(attempt-1), reconnectMaxDelay)
+		fmt.Fprintf(os.Stderr, "%s [%s] Session dropped (%v), reconnecting in %s (attempt %d/%d)...\n",
+			warnIcon(), time.Now().Format("15:04:05"), err, delay, attempt, reconnectAttempts)
+		time.Sleep(delay)
+		stderr.Reset()
+		err = runSessionProcess(ctx, region, clusterArn, taskArn, containerName, command, args, &stderr)
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return withExitCode(exitGeneralError, fmt.Errorf("session terminated: exceeded --session-timeout of %s", sessionTimeout))
+		}
+		// KMS problems also show up as AccessDeniedException, so check for them first
+		if isKMSFailure(stderr.String()) {
+			fmt.Fprintf(os.Stderr, "%s The session could not be encrypted with the cluster's KMS key.\n", errorIcon())
+			fmt.Fprintln(os.Stderr, "   Check the key in the cluster's executeCommandConfiguration (aws ecs describe-clusters --include CONFIGURATIONS):")
+			fmt.Fprintln(os.Stderr, "   - your IAM identity needs kms:GenerateDataKey on it")
+			fmt.Fprintln(os.Stderr, "   - the task role needs kms:Decrypt on it")
+			fmt.Fprintln(os.Stderr, "   - the key policy must allow both, and the key must be enabled")
+			return withExitCode(exitAuthError, errors.New("execute-command failed because of the KMS key"))
+		}
+		if strings.Contains(stderr.String(), "AccessDeniedException") {
+			return withExitCode(exitAuthError, fmt.Errorf("access denied: you need ecs:ExecuteCommand on task %s in cluster %s", taskArn, clusterArn))
+		}
+		if isTaskGone(stderr.String()) {
+			return errTaskGone
+		}
 		if strings.Contains(err.Error(), "is not enabled") {
-			log.Fatalf("❌ Service does not have execute-command enabled: %v", err)
-		} else {
-			log.Fatalf("❌ Failed to start execute-command session: %v", err)
+			return withExitCode(exitGeneralError, fmt.Errorf("service does not have execute-command enabled: %w", err))
 		}
+		return withExitCode(exitGeneralError, fmt.Errorf("failed to start execute-command session: %w", err))
 	}
+	return nil
 }
 
-func chooseCommand() string {
-	fmt.Println("🔍 Choose a command to run:")
-	fmt.Println("1) sh")
-	fmt.Println("2) bash")
-	fmt.Println("3) Enter custom command")
-
-	var choice int
-	fmt.Printf("➡️  Enter the number of your choice: ")
-	fmt.Scanf("%d", &choice)
+// runSessionProcess starts the session once, through the aws CLI or with --native the plugin,
+// copying its stderr into stderr
+func runSessionProcess(ctx context.Context, region string, clusterArn string, taskArn string, containerName string, command string, args []string, stderr *bytes.Buffer) error {
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	cmd.Env = childEnv()
+	if nativeSession {
+		var err error
+		started := time.Now()
+		cmd, err = nativeSessionCommand(ctx, region, clusterArn, taskArn, containerName, command)
+		recordMetric("exec-setup", started, err)
+		// The SDK's errors go through the same checks as the CLI's stderr
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %v\n", errorIcon(), err)
+			stderr.WriteString(err.Error())
+			return err
+		}
+	}
 
-	switch choice {
-	case 1:
-		return "sh"
-	case 2:
-		return "bash"
-	case 3:
-		var customCommand string
-		fmt.Printf("➡️  Enter your custom command: ")
-		fmt.Scanf("%s", &customCommand)
-		return customCommand
-	default:
-		fmt.Println("❌ Invalid choice, defaulting to 'sh'")
-		return "sh"
+	// The process group lets --session-timeout stop session-manager-plugin too, not only the CLI
+	runInOwnProcessGroup(cmd, interactiveSession)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	// ECS only supports interactive exec sessions, so --interactive=false still passes --interactive
+	// to the CLI but leaves stdin unconnected, which ends the session when the command finishes
+	if interactiveSession {
+		cmd.Stdin = os.Stdin
+		defer protectTerminal(cmd)()
+		if nativeSession {
+			statusf("%s Starting session-manager-plugin session...\n", launchIcon())
+		} else {
+			statusf("%s Starting AWS CLI execute-command session...\n", launchIcon())
+		}
+	} else {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "%s Running %q in %s without a terminal...\n", launchIcon(), command, containerName)
+		}
 	}
+	started := time.Now()
+	err := cmd.Run()
+	if interactiveSession {
+		reclaimTerminal()
+	}
+	recordMetric("session", started, err)
+	return err
 }
 
-func chooseOption(entity string, options []string) string {
-	fmt.Printf("🔍 Choose a %s:\n", entity)
-	for i, option := range options {
-		fmt.Printf("%s[%d]%s %s\n", yellow(), i+1, reset(), option)
+// canReconnect reports whether a failed session is worth starting again for --reconnect. Failures
+// that would only happen again, like missing permissions or a stopped task, are not retried.
+func canReconnect(ctx context.Context, stderr string) bool {
+	if ctx.Err() != nil {
+		return false
 	}
+	return !isKMSFailure(stderr) && !isTaskGone(stderr) &&
+		!strings.Contains(stderr, "AccessDeniedException") && !strings.Contains(stderr, "is not enabled")
+}
 
-	var choice int
-	fmt.Printf("➡️  Enter the number of your choice: ")
-	fmt.Scanf("%d", &choice)
-
-	return options[choice-1]
+// childEnv is the environment for spawned AWS CLI processes. It is the full environment of
+// ecs-session, so settings injected by wrappers such as aws-vault reach the CLI, with the
+// credentials replaced by the ones the session was validated with.
+func childEnv() []string {
+	env := os.Environ()
+	if dualStack {
+		env = setEnv(env, "AWS_USE_DUALSTACK_ENDPOINT", "true")
+	}
+	if profile != "" {
+		env = setEnv(env, "AWS_PROFILE", profile)
+	}
+	return exportCredentials(env)
 }
 
-func chooseOptionWithBack(entity string, options []string) string {
-	fmt.Printf("🔍 Choose a %s (or type '0' to go back):\n", entity)
-	fmt.Printf("%s[0]%s Go back\n", yellow(), reset())
+// setEnv returns env with name set to value, replacing any earlier value
+func setEnv(env []string, name string, value string) []string {
+	env = slices.DeleteFunc(env, func(entry string) bool {
+		return strings.HasPrefix(entry, name+"=")
+	})
+	return append(env, name+"="+value)
+}
 
-	for i, option := range options {
-		fmt.Printf("%s[%d]%s %s\n", yellow(), i+1, reset(), option)
+// shellQuote quotes s for a POSIX shell, leaving it as-is when no quoting is needed
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:=@%+,") == "" {
+		return s
 	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
-	var choice int
-	fmt.Printf("➡️  Enter the number of your choice: ")
-	fmt.Scanf("%d", &choice)
+// shellJoin quotes each argument and joins them into a copy-pasteable command line
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
 
-	if choice == 0 {
-		return "BACK"
+// filterExtraSessionArgs drops pass-through arguments that would duplicate flags we already set
+func filterExtraSessionArgs(args []string) []string {
+	var filtered []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(args[i], "=")
+		if !managedSessionFlags[name] {
+			filtered = append(filtered, args[i])
+			continue
+		}
+		log.Printf("%s Ignoring pass-through argument %s: it is set by ecs-session", warnIcon(), name)
+		// Skip the flag's separate value too, if it has one
+		if !hasValue && name != "--interactive" && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			i++
+		}
 	}
-	return options[choice-1]
+	return filtered
 }
 
-func yellow() string {
-	return "\033[33m"
+// parseMultiChoice turns comma-separated option numbers into the options they refer to, skipping repeats
+func parseMultiChoice(input string, options []string) ([]string, bool) {
+	var picked []string
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		choice, err := strconv.Atoi(part)
+		if err != nil || choice < 1 || choice > len(options) {
+			return nil, false
+		}
+		if !seen[choice] {
+			seen[choice] = true
+			picked = append(picked, options[choice-1])
+		}
+	}
+	return picked, len(picked) > 0
 }
 
-func reset() string {
-	return "\033[0m"
+// statusf prints a decorative status line, unless --quiet is set
+func statusf(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
 }
 
-// clearScreen clears the terminal screen
+// clearScreen clears the terminal screen. Clearing is cosmetic, so failures are ignored.
 func clearScreen() {
-	cmd := exec.Command("clear")
+	if os.Getenv("TERM") == "dumb" || !isTerminal(os.Stdout) {
+		return
+	}
+
+	// Older Windows consoles don't understand ANSI escapes, so use cls there
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", "cls")
+		cmd := exec.Command("cmd", "/c", "cls")
+		cmd.Stdout = os.Stdout
+		_ = cmd.Run()
+		return
 	}
-	cmd.Stdout = os.Stdout
-	cmd.Run()
+
+	fmt.Print("\033[H\033[2J")
 }
 
-// Load the default region from a local file
-func loadDefaultRegion() string {
-	data, err := ioutil.ReadFile(defaultRegionFile)
+// isTerminal reports whether the file is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
 	if err != nil {
-		if !os.IsNotExist(err) {
-			log.Printf("⚠️  Could not read default region file: %v", err)
-		}
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Load the default region from the config file, or "" with --no-save-region
+func loadDefaultRegion(settings *appConfig) string {
+	if noSave {
 		return ""
 	}
-	return strings.TrimSpace(string(data))
+	return settings.Region
 }
 
-// Save the region to a local file as the default for next time
+// Save the region to the config file as the default for next time
 func saveRegionAsDefault(region string) {
-	fmt.Printf("ℹ️  Would you like to save '%s' as the default region for next time? (y/n): ", region)
-	var saveDefault string
-	fmt.Scanf("%s", &saveDefault)
-
-	if strings.ToLower(saveDefault) == "y" {
-		err := ioutil.WriteFile(defaultRegionFile, []byte(region), 0644)
+	if confirm(fmt.Sprintf("%s Would you like to save '%s' as the default region for next time? (y/n): ", infoIcon(), region)) {
+		cfg, err := loadConfig()
+		if err == nil {
+			cfg.Region = region
+			err = saveConfig(cfg)
+		}
 		if err != nil {
-			log.Printf("⚠️  Could not save default region: %v", err)
+			log.Printf("%s Could not save default region: %v", warnIcon(), err)
 		} else {
-			fmt.Println("✅ Default region saved.")
+			fmt.Printf("%s Default region saved.\n", okIcon())
 		}
 	}
 }
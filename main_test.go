@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestArnResourceName(t *testing.T) {
+	tests := []struct {
+		name         string
+		arn          string
+		resourceType string
+		want         string
+	}{
+		{name: "cluster", arn: "arn:aws:ecs:us-east-1:123456789012:cluster/prod", resourceType: "cluster", want: "prod"},
+		{name: "long service", arn: "arn:aws:ecs:us-east-1:123456789012:service/prod/api", resourceType: "service", want: "api"},
+		{name: "short service", arn: "arn:aws:ecs:us-east-1:123456789012:service/api", resourceType: "service", want: "api"},
+		{name: "task stays whole", arn: "arn:aws:ecs:us-east-1:123456789012:task/prod/0123abcd", resourceType: "service", want: "arn:aws:ecs:us-east-1:123456789012:task/prod/0123abcd"},
+		{name: "service ARN asked for a cluster", arn: "arn:aws:ecs:us-east-1:123456789012:service/prod/api", resourceType: "cluster", want: "arn:aws:ecs:us-east-1:123456789012:service/prod/api"},
+		{name: "cluster name with slash", arn: "arn:aws:ecs:us-east-1:123456789012:cluster/team/prod", resourceType: "cluster", want: "team/prod"},
+		{name: "cluster name with colon", arn: "arn:aws:ecs:us-east-1:123456789012:cluster/team:prod", resourceType: "cluster", want: "team:prod"},
+		{name: "service in cluster with slash", arn: "arn:aws:ecs:us-east-1:123456789012:service/team/prod/api", resourceType: "service", want: "api"},
+		{name: "GovCloud cluster", arn: "arn:aws-us-gov:ecs:us-gov-west-1:123456789012:cluster/prod", resourceType: "cluster", want: "prod"},
+		{name: "GovCloud service", arn: "arn:aws-us-gov:ecs:us-gov-west-1:123456789012:service/prod/api", resourceType: "service", want: "api"},
+		{name: "China cluster", arn: "arn:aws-cn:ecs:cn-north-1:123456789012:cluster/prod", resourceType: "cluster", want: "prod"},
+		{name: "China short service", arn: "arn:aws-cn:ecs:cn-northwest-1:123456789012:service/api", resourceType: "service", want: "api"},
+		{name: "plain name", arn: "prod", resourceType: "cluster", want: "prod"},
+		{name: "missing resource", arn: "arn:aws:ecs:us-east-1:123456789012", resourceType: "cluster", want: "arn:aws:ecs:us-east-1:123456789012"},
+		{name: "not an ARN", arn: "cluster/prod", resourceType: "cluster", want: "cluster/prod"},
+		{name: "empty", arn: "", resourceType: "service", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := arnResourceName(tt.arn, tt.resourceType); got != tt.want {
+				t.Errorf("arnResourceName(%q, %q) = %q, want %q", tt.arn, tt.resourceType, got, tt.want)
+			}
+		})
+	}
+}
+
+// envValue returns the value of name in env and whether it is set, failing when it is set twice
+func envValue(t *testing.T, env []string, name string) (string, bool) {
+	t.Helper()
+	value, found := "", false
+	for _, entry := range env {
+		if entryName, entryValue, _ := strings.Cut(entry, "="); entryName == name {
+			if found {
+				t.Errorf("%s is set more than once", name)
+			}
+			value, found = entryValue, true
+		}
+	}
+	return value, found
+}
+
+func TestChildEnv(t *testing.T) {
+	t.Setenv("AWS_VAULT", "dev")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAVAULT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "vault-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "vault-token")
+	t.Setenv("AWS_PROFILE", "from-env")
+	t.Setenv("AWS_REGION", "us-east-1")
+	previousProfile, previousCredentials := profile, sessionCredentials
+	t.Cleanup(func() { profile, sessionCredentials = previousProfile, previousCredentials })
+
+	t.Run("passes the environment through", func(t *testing.T) {
+		profile, sessionCredentials = "", nil
+		env := childEnv()
+		for name, want := range map[string]string{
+			"AWS_VAULT":             "dev",
+			"AWS_ACCESS_KEY_ID":     "AKIAVAULT",
+			"AWS_SECRET_ACCESS_KEY": "vault-secret",
+			"AWS_SESSION_TOKEN":     "vault-token",
+			"AWS_PROFILE":           "from-env",
+		} {
+			if got, _ := envValue(t, env, name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+	})
+
+	t.Run("--profile replaces AWS_PROFILE", func(t *testing.T) {
+		profile, sessionCredentials = "from-flag", nil
+		if got, _ := envValue(t, childEnv(), "AWS_PROFILE"); got != "from-flag" {
+			t.Errorf("AWS_PROFILE = %q, want from-flag", got)
+		}
+	})
+
+	t.Run("session credentials replace the environment's", func(t *testing.T) {
+		profile = ""
+		sessionCredentials = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIASESSION", SecretAccessKey: "session-secret"}, nil
+		})
+		env := childEnv()
+		if got, _ := envValue(t, env, "AWS_ACCESS_KEY_ID"); got != "AKIASESSION" {
+			t.Errorf("AWS_ACCESS_KEY_ID = %q, want AKIASESSION", got)
+		}
+		if got, _ := envValue(t, env, "AWS_SECRET_ACCESS_KEY"); got != "session-secret" {
+			t.Errorf("AWS_SECRET_ACCESS_KEY = %q, want session-secret", got)
+		}
+		if _, ok := envValue(t, env, "AWS_SESSION_TOKEN"); ok {
+			t.Error("the environment's session token was left over")
+		}
+	})
+
+	t.Run("--dualstack replaces AWS_USE_DUALSTACK_ENDPOINT", func(t *testing.T) {
+		t.Setenv("AWS_USE_DUALSTACK_ENDPOINT", "false")
+		previous := dualStack
+		dualStack = true
+		t.Cleanup(func() { dualStack = previous })
+		profile, sessionCredentials = "", nil
+		if got, _ := envValue(t, childEnv(), "AWS_USE_DUALSTACK_ENDPOINT"); got != "true" {
+			t.Errorf("AWS_USE_DUALSTACK_ENDPOINT = %q, want true", got)
+		}
+	})
+
+	t.Run("the session region beats AWS_REGION", func(t *testing.T) {
+		profile, sessionCredentials = "", nil
+		if got, _ := envValue(t, childEnv(), "AWS_REGION"); got != "us-east-1" {
+			t.Errorf("AWS_REGION = %q, want it passed through", got)
+		}
+		// The CLI prefers --region over AWS_REGION, so the session runs in the region picked
+		args := execCommandArgs("eu-west-1", "prod", "task", "app", "sh")
+		if i := slices.Index(args, "--region"); i < 0 || args[i+1] != "eu-west-1" {
+			t.Errorf("execute-command arguments don't set --region eu-west-1: %v", args)
+		}
+	})
+}
+
+func TestServiceInputsUseShortName(t *testing.T) {
+	for _, service := range []string{
+		"api",
+		"arn:aws:ecs:us-east-1:123456789012:service/prod/api",
+		"arn:aws:ecs:us-east-1:123456789012:service/api",
+	} {
+		if got := describeServiceInput("prod", service).Services; !slices.Equal(got, []string{"api"}) {
+			t.Errorf("DescribeServices for %q gets services %v, want [api]", service, got)
+		}
+		input := serviceTasksInput("prod", service)
+		if got := aws.ToString(input.ServiceName); got != "api" {
+			t.Errorf("ListTasks for %q gets service %q, want api", service, got)
+		}
+		if got := aws.ToString(input.Cluster); got != "prod" {
+			t.Errorf("ListTasks for %q gets cluster %q, want prod", service, got)
+		}
+	}
+}
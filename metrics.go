@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// metricsFile is where --metrics-file writes the timings of the run, empty when off
+var metricsFile string
+
+// metricKey groups the timings of one operation by whether it succeeded
+type metricKey struct {
+	Operation string
+	Success   bool
+}
+
+// metricTotals are the count and summed duration of one operation
+type metricTotals struct {
+	Count int
+	Total time.Duration
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   = map[metricKey]*metricTotals{}
+)
+
+// recordMetric adds the time since started to the operation's totals. It does nothing without --metrics-file.
+func recordMetric(operation string, started time.Time, err error) {
+	if metricsFile == "" {
+		return
+	}
+	elapsed := time.Since(started)
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	key := metricKey{Operation: operation, Success: err == nil}
+	totals, ok := metrics[key]
+	if !ok {
+		totals = &metricTotals{}
+		metrics[key] = totals
+	}
+	totals.Count++
+	totals.Total += elapsed
+}
+
+// metricsAPIOptions adds the timing middleware to the SDK clients
+func metricsAPIOptions() []func(*middleware.Stack) error {
+	return []func(*middleware.Stack) error{addMetricsMiddleware}
+}
+
+// addMetricsMiddleware times every SDK call, retries included, as e.g. ecs:ListServices
+func addMetricsMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ecsSessionMetrics",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			started := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			operation := strings.ToLower(awsmiddleware.GetServiceID(ctx)) + ":" + awsmiddleware.GetOperationName(ctx)
+			recordMetric(operation, started, err)
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// writeMetrics writes the totals in the Prometheus text format, so the file can be picked up by
+// node_exporter's textfile collector or read with any Prometheus parser. Failing to write it only
+// warns, the run itself went fine.
+func writeMetrics() {
+	if metricsFile == "" {
+		return
+	}
+
+	metricsMu.Lock()
+	keys := make([]metricKey, 0, len(metrics))
+	for key := range metrics {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Operation != keys[j].Operation {
+			return keys[i].Operation < keys[j].Operation
+		}
+		return !keys[i].Success && keys[j].Success
+	})
+
+	var out strings.Builder
+	out.WriteString("# HELP ecs_session_operation_duration_seconds Time spent in AWS calls and sessions during one ecs-session run.\n")
+	out.WriteString("# TYPE ecs_session_operation_duration_seconds summary\n")
+	for _, key := range keys {
+		labels := fmt.Sprintf("{operation=%q,success=\"%t\"}", key.Operation, key.Success)
+		fmt.Fprintf(&out, "ecs_session_operation_duration_seconds_sum%s %.6f\n", labels, metrics[key].Total.Seconds())
+		fmt.Fprintf(&out, "ecs_session_operation_duration_seconds_count%s %d\n", labels, metrics[key].Count)
+	}
+	metricsMu.Unlock()
+
+	if err := writeMetricsFile(metricsFile, []byte(out.String())); err != nil {
+		log.Printf("%s Unable to write --metrics-file %s: %v", warnIcon(), metricsFile, err)
+	}
+}
+
+// writeMetricsFile replaces a regular file atomically. Anything else, like a FIFO or /dev/stdout,
+// can't be renamed over, so it's written directly and without blocking when nothing reads it.
+func writeMetricsFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().IsRegular() {
+		return writeFileAtomic(path, data, 0644)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// exitWith writes the --metrics-file and exits with the code
+func exitWith(code int) {
+	writeMetrics()
+	os.Exit(code)
+}
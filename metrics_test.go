@@ -0,0 +1,54 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("regular file", func(t *testing.T) {
+		path := filepath.Join(dir, "metrics.prom")
+		if err := os.WriteFile(path, []byte("old contents that are longer\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeMetricsFile(path, []byte("new\n")); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "new\n" {
+			t.Errorf("file holds %q, want new", data)
+		}
+	})
+
+	t.Run("FIFO", func(t *testing.T) {
+		path := filepath.Join(dir, "metrics.fifo")
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			t.Skipf("no FIFOs here: %v", err)
+		}
+		read := make(chan string)
+		go func() {
+			data, _ := os.ReadFile(path)
+			read <- string(data)
+		}()
+		// Wait for the reader to open its end, otherwise the non-blocking open fails
+		deadline := time.Now().Add(5 * time.Second)
+		for err := writeMetricsFile(path, []byte("metrics\n")); err != nil; err = writeMetricsFile(path, []byte("metrics\n")) {
+			if time.Now().After(deadline) {
+				t.Fatalf("no reader opened the FIFO: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := <-read; got != "metrics\n" {
+			t.Errorf("reader got %q, want metrics", got)
+		}
+		if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+			t.Errorf("the FIFO was replaced: %v", info.Mode())
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// nativeSession starts sessions with the SDK and session-manager-plugin instead of the aws CLI
+var nativeSession bool
+
+// nativeSessionCommand calls ecs:ExecuteCommand through the SDK and returns the
+// session-manager-plugin command that connects to the session, the way the aws CLI does it
+func nativeSessionCommand(ctx context.Context, region string, clusterArn string, taskArn string, containerName string, command string) (*exec.Cmd, error) {
+	pluginPath, err := exec.LookPath("session-manager-plugin")
+	if err != nil {
+		return nil, fmt.Errorf("--native needs session-manager-plugin in PATH: %v", err)
+	}
+
+	clients, err := clientsFor(region)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config: %v", err)
+	}
+	client := clients.ECS
+
+	// The plugin's target names the container by its runtime ID
+	tasks, err := describeTasks(client, clusterArn, []string{taskArn})
+	if err != nil {
+		return nil, err
+	}
+	runtimeID := ""
+	for _, task := range tasks {
+		for _, container := range task.Containers {
+			if aws.ToString(container.Name) == containerName {
+				runtimeID = aws.ToString(container.RuntimeId)
+			}
+		}
+	}
+	if runtimeID == "" {
+		return nil, fmt.Errorf("container %s of task %s has no runtime ID: task not found or not running", containerName, taskArn)
+	}
+
+	output, err := client.ExecuteCommand(ctx, &ecs.ExecuteCommandInput{
+		Cluster:     &clusterArn,
+		Task:        &taskArn,
+		Container:   &containerName,
+		Command:     &command,
+		Interactive: true,
+	})
+	if err != nil {
+		return nil, wrapAWSError(err, "ecs:ExecuteCommand", "task "+taskArn+" in cluster "+clusterArn)
+	}
+
+	session, err := json.Marshal(map[string]string{
+		"SessionId":  aws.ToString(output.Session.SessionId),
+		"StreamUrl":  aws.ToString(output.Session.StreamUrl),
+		"TokenValue": aws.ToString(output.Session.TokenValue),
+	})
+	if err != nil {
+		return nil, err
+	}
+	taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
+	target, err := json.Marshal(map[string]string{
+		"Target": fmt.Sprintf("ecs:%s_%s_%s", resourceName(clusterArn, "cluster"), taskID, runtimeID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := ecsEndpoint(ctx, client.Options())
+	if err != nil {
+		return nil, err
+	}
+
+	// These are the arguments the aws CLI hands to the plugin for execute-command
+	cmd := exec.CommandContext(ctx, pluginPath, string(session), region, "StartSession", activeProfile(), string(target), endpoint)
+	cmd.Env = childEnv()
+	runInOwnProcessGroup(cmd, false)
+	return cmd, nil
+}
+
+// ecsEndpoint asks the SDK's resolver for the ECS endpoint the client calls, which differs by
+// partition (amazonaws.com.cn in China) and with dual-stack or FIPS endpoints turned on
+func ecsEndpoint(ctx context.Context, options ecs.Options) (string, error) {
+	endpoint, err := ecs.NewDefaultEndpointResolverV2().ResolveEndpoint(ctx, ecs.EndpointParameters{
+		Region:       aws.String(options.Region),
+		UseDualStack: aws.Bool(options.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled),
+		UseFIPS:      aws.Bool(options.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled),
+		Endpoint:     options.BaseEndpoint,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the ECS endpoint for %s: %w", options.Region, err)
+	}
+	return endpoint.URI.String(), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+func TestECSEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ecs.Options
+		want    string
+	}{
+		{name: "commercial", options: ecs.Options{Region: "eu-west-1"}, want: "https://ecs.eu-west-1.amazonaws.com"},
+		{name: "China", options: ecs.Options{Region: "cn-north-1"}, want: "https://ecs.cn-north-1.amazonaws.com.cn"},
+		{name: "GovCloud", options: ecs.Options{Region: "us-gov-west-1"}, want: "https://ecs.us-gov-west-1.amazonaws.com"},
+		{
+			name: "dual-stack",
+			options: ecs.Options{Region: "eu-west-1", EndpointOptions: ecs.EndpointResolverOptions{
+				UseDualStackEndpoint: aws.DualStackEndpointStateEnabled,
+			}},
+			want: "https://ecs.eu-west-1.api.aws",
+		},
+		{
+			name:    "custom endpoint",
+			options: ecs.Options{Region: "eu-west-1", BaseEndpoint: aws.String("http://localhost:4566")},
+			want:    "http://localhost:4566",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ecsEndpoint(context.Background(), tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ecsEndpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
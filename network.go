@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// taskNetworkInterfaceID returns the ID of the task's elastic network interface, if it has one
+func taskNetworkInterfaceID(task types.Task) string {
+	for _, attachment := range task.Attachments {
+		if aws.ToString(attachment.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, detail := range attachment.Details {
+			if aws.ToString(detail.Name) == "networkInterfaceId" {
+				return aws.ToString(detail.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// describePublicIPs maps the network interfaces of the tasks to their public IPs. ECS doesn't
+// report public IPs itself, so they are looked up in EC2. Interfaces without one are left out.
+func describePublicIPs(client *ec2.Client, tasks []types.Task) (map[string]string, error) {
+	const batchSize = 100
+
+	var interfaceIDs []string
+	for _, task := range tasks {
+		if id := taskNetworkInterfaceID(task); id != "" {
+			interfaceIDs = append(interfaceIDs, id)
+		}
+	}
+
+	publicIPs := make(map[string]string)
+	for start := 0; start < len(interfaceIDs); start += batchSize {
+		end := min(start+batchSize, len(interfaceIDs))
+		output, err := client.DescribeNetworkInterfaces(context.TODO(), &ec2.DescribeNetworkInterfacesInput{
+			NetworkInterfaceIds: interfaceIDs[start:end],
+		})
+		if err != nil {
+			return publicIPs, wrapAWSError(err, "ec2:DescribeNetworkInterfaces", "the tasks' network interfaces")
+		}
+		for _, networkInterface := range output.NetworkInterfaces {
+			if networkInterface.Association != nil && networkInterface.Association.PublicIp != nil {
+				publicIPs[aws.ToString(networkInterface.NetworkInterfaceId)] = aws.ToString(networkInterface.Association.PublicIp)
+			}
+		}
+	}
+	return publicIPs, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// insideTmux reports whether ecs-session runs in a tmux session we can split
+func insideTmux() bool {
+	if os.Getenv("TMUX") == "" {
+		return false
+	}
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+// runMultipleSessions opens a session in each container. Inside tmux every extra container gets
+// its own pane next to this one, otherwise the sessions run one after another.
+func runMultipleSessions(region string, clusterArn string, taskArn string, containers []string, commands []string) error {
+	// Without a terminal to hand over there is nothing to wait for between containers
+	if dryRun || !interactiveSession {
+		for i, container := range containers {
+			if err := runAWSSession(region, clusterArn, taskArn, container, commands[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if insideTmux() {
+		for i := 1; i < len(containers); i++ {
+			if err := openTmuxPane(region, clusterArn, taskArn, containers[i], commands[i]); err != nil {
+				log.Printf("%s Could not open a tmux pane for %s: %v", warnIcon(), containers[i], err)
+			}
+		}
+		return runAWSSession(region, clusterArn, taskArn, containers[0], commands[0])
+	}
+
+	for i, container := range containers {
+		if i > 0 {
+			waitForEnter(fmt.Sprintf("%s Press Enter to open a session in container %s (%d/%d): ", promptIcon(), container, i+1, len(containers)))
+		}
+		statusf("%s Container %s (%d/%d)\n", infoIcon(), container, i+1, len(containers))
+		if err := runAWSSession(region, clusterArn, taskArn, container, commands[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openTmuxPane splits the current tmux window and starts a session for the container in the new pane
+func openTmuxPane(region string, clusterArn string, taskArn string, containerName string, command string) error {
+	if err := checkDeniedCommand(command); err != nil {
+		return err
+	}
+
+	args := []string{"split-window", "-h"}
+	// New panes get the tmux server's environment, so hand over the AWS settings we run with
+	for _, env := range forwardedAWSEnv("pane") {
+		args = append(args, "-e", env)
+	}
+	args = append(args, shellJoin(append([]string{"aws"}, execCommandArgs(region, clusterArn, taskArn, containerName, command)...)))
+
+	if output, err := exec.Command("tmux", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	// Keep the panes evenly sized however many containers were picked
+	return exec.Command("tmux", "select-layout", "tiled").Run()
+}
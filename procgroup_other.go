@@ -0,0 +1,25 @@
+//go:build !unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// runInOwnProcessGroup only bounds how long Wait waits for the output pipes once the command was
+// killed; outside Unix there are no process groups to put the command in
+func runInOwnProcessGroup(cmd *exec.Cmd, foreground bool) {
+	cmd.WaitDelay = sessionWaitDelay
+}
+
+// signalProcessGroup kills the command, the closest there is to signalling its process group
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// reclaimTerminal has nothing to do without process groups
+func reclaimTerminal() {}
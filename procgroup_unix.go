@@ -0,0 +1,47 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// runInOwnProcessGroup starts the command in a process group of its own, so cancelling its
+// context also stops what it starts: the aws CLI leaves session-manager-plugin running otherwise.
+// A foreground command also gets the terminal, which the caller takes back with reclaimTerminal.
+func runInOwnProcessGroup(cmd *exec.Cmd, foreground bool) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if foreground && isTerminal(os.Stdin) {
+		// Ctty is the child's stdin, the terminal it reads keys from
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = 0
+	}
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = sessionWaitDelay
+}
+
+// signalProcessGroup sends the signal to every process in the command's process group
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// reclaimTerminal puts our process group back in the foreground after a foreground command ended.
+// Doing that from the background raises SIGTTOU, which would stop us, so it's ignored meanwhile.
+func reclaimTerminal() {
+	if !isTerminal(os.Stdin) {
+		return
+	}
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(int(os.Stdin.Fd()), unix.TIOCSPGRP, syscall.Getpgrp())
+}
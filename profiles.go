@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// chooseProfile shows the profile menu unless --profile was passed
+	chooseProfile bool
+	// chooseProfileAlways is choose_profile from the config file: show the menu whenever no profile is set
+	chooseProfileAlways bool
+)
+
+// sharedFilePath returns the file named by the environment variable, or the default file in ~/.aws
+func sharedFilePath(envVar string, name string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// listAWSProfiles returns the profile names in the shared config and credentials files, sorted.
+// Missing files are skipped.
+func listAWSProfiles() ([]string, error) {
+	seen := make(map[string]bool)
+	files := []struct {
+		path string
+		// Sections of the config file other than [default] are named "profile <name>"
+		config bool
+	}{
+		{sharedFilePath("AWS_CONFIG_FILE", "config"), true},
+		{sharedFilePath("AWS_SHARED_CREDENTIALS_FILE", "credentials"), false},
+	}
+
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		f, err := os.Open(file.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+				continue
+			}
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			if file.config && section != "default" {
+				// Skips [sso-session ...] and [services ...] too
+				name, ok := strings.CutPrefix(section, "profile ")
+				if !ok {
+					continue
+				}
+				section = strings.TrimSpace(name)
+			}
+			if section != "" {
+				seen[section] = true
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	profiles := make([]string, 0, len(seen))
+	for name := range seen {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prompter reads answers from in and writes menus and prompts to out, so the interactive flow can
+// be driven by something other than the terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// timeout ends the run when a prompt goes unanswered this long, zero waits forever
+	timeout time.Duration
+
+	// exit ends the run on a prompt timeout or closed input
+	exit func(code int)
+}
+
+// newPrompter returns a prompter reading from in and writing to out
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out, exit: exitWith}
+}
+
+// stdio is the prompter on the terminal used by the package-level prompt functions
+var stdio = newPrompter(os.Stdin, os.Stdout)
+
+// chooseCommand asks which command to run. A non-empty preferred command is used when the user just presses Enter.
+func (p *prompter) chooseCommand(preferred string) string {
+	fmt.Fprintf(p.out, "%s Choose a command to run:\n", searchIcon())
+	fmt.Fprintln(p.out, "1) sh")
+	fmt.Fprintln(p.out, "2) bash")
+	fmt.Fprintln(p.out, "3) Enter custom command")
+	fmt.Fprintln(p.out, "4) View container logs")
+	fmt.Fprintln(p.out, "5) Detect the available shell")
+
+	prompt := fmt.Sprintf("%s Enter the number of your choice: ", promptIcon())
+	var input string
+	if preferred != "" {
+		fmt.Fprintf(p.out, "%s Press Enter to run your preferred command: %s\n", infoIcon(), preferred)
+		fmt.Fprint(p.out, prompt)
+		line, err := p.readLine()
+		if err != nil {
+			p.exitOnClosedInput()
+		}
+		if line == "" {
+			return preferred
+		}
+		input = line
+	} else {
+		input = p.requireInput(prompt)
+	}
+	choice, _ := strconv.Atoi(input)
+
+	switch choice {
+	case 1:
+		return "sh"
+	case 2:
+		return "bash"
+	case 3:
+		// requireInput asks again until the command isn't empty
+		return p.requireInput(fmt.Sprintf("%s Enter your custom command: ", promptIcon()))
+	case 4:
+		return viewLogsCommand
+	case 5:
+		return detectShellCommand
+	default:
+		fmt.Fprintf(p.out, "%s Invalid choice, defaulting to 'sh'\n", errorIcon())
+		return "sh"
+	}
+}
+
+func (p *prompter) chooseOption(entity string, options []string) string {
+	fmt.Fprintf(p.out, "%s Choose a %s:\n", searchIcon(), entity)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "%s[%d]%s %s\n", yellow(), i+1, reset(), option)
+	}
+
+	for {
+		input := p.requireInput(fmt.Sprintf("%s Enter the number or name of your choice: ", promptIcon()))
+		choice, err := strconv.Atoi(input)
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return options[choice-1]
+		}
+		if match, ok := p.matchOptionName(input, options, options); ok {
+			return match
+		}
+		fmt.Fprintf(p.out, "%s Invalid choice, please try again\n", errorIcon())
+	}
+}
+
+// matchOptionName resolves a typed name to the option whose name or label it equals, ignoring case.
+// Unknown names and names matching more than one option are not resolved.
+func (p *prompter) matchOptionName(input string, options []string, labels []string) (string, bool) {
+	var matches []string
+	for i, option := range options {
+		if strings.EqualFold(input, option) || strings.EqualFold(input, labels[i]) {
+			matches = append(matches, option)
+		}
+	}
+	if len(matches) > 1 {
+		fmt.Fprintf(p.out, "%s %q matches %d options, enter its number instead\n", warnIcon(), input, len(matches))
+		return "", false
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// menuAction is how the user left a menu with navigation
+type menuAction int
+
+const (
+	menuPicked  menuAction = iota // one or more options were picked
+	menuBack                      // go back a level
+	menuRefresh                   // reload the options
+	menuJump                      // jump to the breadcrumb level in JumpKey
+)
+
+// menuChoice is the answer to a menu with navigation. Navigation is its own action, so an option
+// can't be mistaken for it whatever its name.
+type menuChoice struct {
+	Action  menuAction
+	Values  []string
+	JumpKey string
+}
+
+// Value returns the picked option, or the first of several
+func (c menuChoice) Value() string {
+	if len(c.Values) == 0 {
+		return ""
+	}
+	return c.Values[0]
+}
+
+// chooseOptionWithBack shows the options a page at a time, numbered across all pages
+func (p *prompter) chooseOptionWithBack(entity string, options []string) menuChoice {
+	return p.chooseLabeledOptionWithBack(entity, options, options, -1)
+}
+
+// chooseLabeledOptionWithBack is chooseOptionWithBack with display labels for each option.
+// When defaultIndex is not negative, pressing Enter selects that option.
+func (p *prompter) chooseLabeledOptionWithBack(entity string, options []string, labels []string, defaultIndex int) menuChoice {
+	return p.chooseLabeledOptionsWithBack(entity, options, labels, defaultIndex, false)
+}
+
+// chooseLabeledOptionsWithBack is chooseLabeledOptionWithBack that, when multi is set, also accepts
+// comma-separated numbers and returns every option picked
+func (p *prompter) chooseLabeledOptionsWithBack(entity string, options []string, labels []string, defaultIndex int, multi bool) menuChoice {
+	return p.chooseFromMenu(entity, options, labels, defaultIndex, multi, 0)
+}
+
+// chooseFromMenu draws the menu behind the choose functions. The first pinned options are set
+// apart from the rest by a line, numbering carries on across it.
+func (p *prompter) chooseFromMenu(entity string, options []string, labels []string, defaultIndex int, multi bool, pinned int) menuChoice {
+	perPage := pageSize
+	if perPage <= 0 {
+		perPage = len(options)
+	}
+	pages := max(1, (len(options)+perPage-1)/perPage)
+	page := 0
+	if defaultIndex >= 0 {
+		page = defaultIndex / perPage
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s Choose a %s (or type '0' to go back):\n", searchIcon(), entity)
+		if multi {
+			fmt.Fprintln(p.out, "   (separate numbers with commas to pick several, e.g. 1,2)")
+		}
+		fmt.Fprintf(p.out, "%s[0]%s Go back\n", yellow(), reset())
+		fmt.Fprintf(p.out, "%s[r]%s Refresh\n", yellow(), reset())
+
+		start := page * perPage
+		end := min(start+perPage, len(options))
+		for i := start; i < end; i++ {
+			fmt.Fprintf(p.out, "%s[%d]%s %s\n", yellow(), i+1, reset(), labels[i])
+			if i == pinned-1 && i < end-1 {
+				fmt.Fprintln(p.out, "    ----")
+			}
+		}
+
+		if pages > 1 {
+			fmt.Fprintf(p.out, "-- Page %d/%d --\n", page+1, pages)
+			if page < pages-1 {
+				fmt.Fprintf(p.out, "%s[n]%s Next page\n", yellow(), reset())
+			}
+			if page > 0 {
+				fmt.Fprintf(p.out, "%s[p]%s Previous page\n", yellow(), reset())
+			}
+		}
+
+		prompt := fmt.Sprintf("%s Enter the number or name of your choice: ", promptIcon())
+		if defaultIndex >= 0 {
+			prompt = fmt.Sprintf("%s Enter the number or name of your choice [%d]: ", promptIcon(), defaultIndex+1)
+		}
+
+		// Ask again on an empty line unless there's a default, and treat Ctrl+D as going back
+		var line, input string
+		for input == "" {
+			fmt.Fprint(p.out, prompt)
+			var err error
+			line, err = p.readLine()
+			input = strings.ToLower(line)
+			if input == "" && defaultIndex >= 0 && err == nil {
+				return menuChoice{Action: menuPicked, Values: options[defaultIndex : defaultIndex+1]}
+			}
+			if err != nil {
+				fmt.Fprintln(p.out)
+				return menuChoice{Action: menuBack}
+			}
+		}
+
+		if input == "r" {
+			return menuChoice{Action: menuRefresh}
+		}
+		if _, ok := jumpKeys[input]; ok {
+			return menuChoice{Action: menuJump, JumpKey: input}
+		}
+		if input == "n" && page < pages-1 {
+			page++
+			continue
+		}
+		if input == "p" && page > 0 {
+			page--
+			continue
+		}
+
+		if multi && strings.Contains(input, ",") {
+			if picked, ok := parseMultiChoice(input, options); ok {
+				return menuChoice{Action: menuPicked, Values: picked}
+			}
+			fmt.Fprintf(p.out, "%s Invalid choice, please try again\n", errorIcon())
+			continue
+		}
+
+		choice, err := strconv.Atoi(input)
+		if err == nil && choice == 0 {
+			return menuChoice{Action: menuBack}
+		}
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return menuChoice{Action: menuPicked, Values: options[choice-1 : choice]}
+		}
+		if match, ok := p.matchOptionName(line, options, labels); ok {
+			return menuChoice{Action: menuPicked, Values: []string{match}}
+		}
+		fmt.Fprintf(p.out, "%s Invalid choice, please try again\n", errorIcon())
+	}
+}
+
+// readInput reads a single line from stdin, trimmed of surrounding whitespace.
+// Once stdin is closed (e.g. Ctrl+D) it returns an empty string.
+func (p *prompter) readInput() string {
+	line, _ := p.readLine()
+	return line
+}
+
+// readLine is readInput that also returns io.EOF once stdin has been closed
+func (p *prompter) readLine() (string, error) {
+	line, err := p.readRawLine()
+	line = strings.TrimSpace(line)
+	if err != nil && line != "" {
+		// Last line without a trailing newline
+		return line, nil
+	}
+	return line, err
+}
+
+// readRawLine reads the next line from in, giving up after the prompter's timeout. The read keeps
+// going in its goroutine after a timeout, which is fine because the run ends right away.
+func (p *prompter) readRawLine() (string, error) {
+	if p.timeout <= 0 {
+		return p.in.ReadString('\n')
+	}
+
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := p.in.ReadString('\n')
+		done <- result{line, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-time.After(p.timeout):
+		fmt.Fprintf(p.out, "\n%s No answer after --prompt-timeout of %s, exiting\n", infoIcon(), p.timeout)
+		p.exit(exitUserAbort)
+		return "", io.EOF
+	}
+}
+
+// requireInput prints the prompt until a non-empty line is entered. Prompts using it have
+// no way back, so closing stdin cancels the whole run.
+func (p *prompter) requireInput(prompt string) string {
+	for {
+		fmt.Fprint(p.out, prompt)
+		line, err := p.readLine()
+		if line != "" {
+			return line
+		}
+		if err != nil {
+			p.exitOnClosedInput()
+		}
+	}
+}
+
+// exitOnClosedInput ends the run when stdin was closed at a prompt with no way back
+func (p *prompter) exitOnClosedInput() {
+	fmt.Fprintf(p.out, "\n%s Input closed, exiting\n", infoIcon())
+	p.exit(exitUserAbort)
+}
+
+// confirm prints the yes/no question and reports whether the answer was y or yes. Anything else,
+// including closed input, is a no.
+func (p *prompter) confirm(question string) bool {
+	fmt.Fprint(p.out, question)
+	answer := strings.ToLower(p.readInput())
+	return answer == "y" || answer == "yes"
+}
+
+// confirmDefaultYes is confirm where just pressing Enter means yes. Closing stdin ends the run,
+// since there's no safe answer to assume.
+func (p *prompter) confirmDefaultYes(question string) bool {
+	fmt.Fprint(p.out, question)
+	answer, err := p.readLine()
+	if err != nil {
+		p.exitOnClosedInput()
+		return false
+	}
+	answer = strings.ToLower(answer)
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// waitForEnter prints the prompt and waits for Enter
+func (p *prompter) waitForEnter(prompt string) {
+	fmt.Fprint(p.out, prompt)
+	if _, err := p.readLine(); err != nil {
+		p.exitOnClosedInput()
+	}
+}
+
+// printf writes informational lines that belong to the prompt that follows them
+func (p *prompter) printf(format string, args ...any) {
+	fmt.Fprintf(p.out, format, args...)
+}
+
+func (p *prompter) enterOrChooseRegion() string {
+	fmt.Fprintf(p.out, "%s Would you like to:\n", searchIcon())
+	fmt.Fprintln(p.out, "1) Enter a region manually (e.g., us-west-2)")
+	fmt.Fprintln(p.out, "2) Choose from your favorite regions")
+	fmt.Fprintln(p.out, "3) Search all regions by code or name")
+
+	choice, _ := strconv.Atoi(p.requireInput(fmt.Sprintf("%s Enter the number of your choice: ", promptIcon())))
+
+	switch choice {
+	case 1:
+		return p.requireInput(fmt.Sprintf("%s Enter your desired region code: ", promptIcon()))
+	case 3:
+		return p.searchRegion()
+	default:
+		return p.chooseRegion()
+	}
+}
+
+// chooseRegion offers the favorite_regions quick picks from the config file
+func (p *prompter) chooseRegion() string {
+	return p.chooseOption("region", favoriteRegions)
+}
+
+// searchRegion lets the user narrow down the known regions by typing part of a code or name
+func (p *prompter) searchRegion() string {
+	matches := knownRegions
+	for {
+		fmt.Fprintf(p.out, "%s Matching regions:\n", searchIcon())
+		for i, r := range matches {
+			fmt.Fprintf(p.out, "%s[%d]%s %s (%s)\n", yellow(), i+1, reset(), r.Code, r.Description)
+		}
+
+		fmt.Fprintf(p.out, "%s Enter a number to choose, text to filter, or nothing to show all: ", promptIcon())
+		input, err := p.readLine()
+		if err != nil {
+			p.exitOnClosedInput()
+		}
+		if choice, err := strconv.Atoi(input); err == nil {
+			if choice >= 1 && choice <= len(matches) {
+				return matches[choice-1].Code
+			}
+			fmt.Fprintf(p.out, "%s Invalid choice, please try again\n", errorIcon())
+			continue
+		}
+
+		filtered := filterRegions(input)
+		if len(filtered) == 0 && regionCodePattern.MatchString(input) {
+			fmt.Fprintf(p.out, "%s %s isn't a region ecs-session knows, using it anyway\n", warnIcon(), input)
+			return input
+		}
+		if len(filtered) == 0 {
+			fmt.Fprintf(p.out, "%s No region matches %q\n", warnIcon(), input)
+			continue
+		}
+		matches = filtered
+	}
+}
+
+// The functions below prompt on the terminal
+
+func chooseCommand(preferred string) string {
+	return stdio.chooseCommand(preferred)
+}
+
+func chooseOption(entity string, options []string) string {
+	return stdio.chooseOption(entity, options)
+}
+
+func chooseOptionWithBack(entity string, options []string) menuChoice {
+	return stdio.chooseOptionWithBack(entity, options)
+}
+
+func chooseLabeledOptionWithBack(entity string, options []string, labels []string, defaultIndex int) menuChoice {
+	return stdio.chooseLabeledOptionWithBack(entity, options, labels, defaultIndex)
+}
+
+func chooseLabeledOptionsWithBack(entity string, options []string, labels []string, defaultIndex int, multi bool) menuChoice {
+	return stdio.chooseLabeledOptionsWithBack(entity, options, labels, defaultIndex, multi)
+}
+
+func choosePinnedOptionWithBack(entity string, options []string, pinned int) menuChoice {
+	return stdio.chooseFromMenu(entity, options, options, -1, false, pinned)
+}
+
+func requireInput(prompt string) string {
+	return stdio.requireInput(prompt)
+}
+
+func exitOnClosedInput() {
+	stdio.exitOnClosedInput()
+}
+
+func confirm(question string) bool {
+	return stdio.confirm(question)
+}
+
+func confirmDefaultYes(question string) bool {
+	return stdio.confirmDefaultYes(question)
+}
+
+func waitForEnter(prompt string) {
+	stdio.waitForEnter(prompt)
+}
+
+func promptf(format string, args ...any) {
+	stdio.printf(format, args...)
+}
+
+func enterOrChooseRegion() string {
+	return stdio.enterOrChooseRegion()
+}
+
+func chooseRegion() string {
+	return stdio.chooseRegion()
+}
+
+func searchRegion() string {
+	return stdio.searchRegion()
+}
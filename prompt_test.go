@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exitCalled is what the test prompter panics with instead of ending the run
+type exitCalled struct {
+	code int
+}
+
+// newTestPrompter returns a prompter answering with the scripted input and recording its output
+func newTestPrompter(input string) (*prompter, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	p := newPrompter(strings.NewReader(input), out)
+	p.exit = func(code int) {
+		panic(exitCalled{code})
+	}
+	return p, out
+}
+
+// expectExit runs fn and returns the exit code it ended the run with, failing when it didn't
+func expectExit(t *testing.T, fn func()) int {
+	t.Helper()
+	code := -1
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				exit, ok := r.(exitCalled)
+				if !ok {
+					panic(r)
+				}
+				code = exit.code
+			}
+		}()
+		fn()
+	}()
+	if code == -1 {
+		t.Fatal("expected the prompt to end the run")
+	}
+	return code
+}
+
+// withPageSize sets the menu page size for one test
+func withPageSize(t *testing.T, size int) {
+	t.Helper()
+	previous := pageSize
+	pageSize = size
+	t.Cleanup(func() { pageSize = previous })
+}
+
+func TestChooseFromMenuPaging(t *testing.T) {
+	withPageSize(t, 2)
+	options := []string{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		pages []string
+	}{
+		{name: "next page", input: "n\n3\n", want: "c", pages: []string{"-- Page 1/3 --", "-- Page 2/3 --"}},
+		{name: "last page", input: "n\nn\n5\n", want: "e", pages: []string{"-- Page 3/3 --"}},
+		{name: "previous page", input: "n\np\n1\n", want: "a", pages: []string{"-- Page 2/3 --"}},
+		{name: "number on another page", input: "4\n", want: "d", pages: []string{"-- Page 1/3 --"}},
+		{name: "no page before the first", input: "p\n2\n", want: "b", pages: []string{"Invalid choice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := newTestPrompter(tt.input)
+			choice := p.chooseOptionWithBack("thing", options)
+			if choice.Action != menuPicked || choice.Value() != tt.want {
+				t.Fatalf("got %+v, want %q picked", choice, tt.want)
+			}
+			for _, page := range tt.pages {
+				if !strings.Contains(out.String(), page) {
+					t.Errorf("output doesn't contain %q:\n%s", page, out)
+				}
+			}
+		})
+	}
+}
+
+func TestChooseFromMenuNavigation(t *testing.T) {
+	withPageSize(t, 0)
+	options := []string{"alpha", "beta"}
+
+	tests := []struct {
+		name    string
+		input   string
+		action  menuAction
+		jumpKey string
+	}{
+		{name: "refresh", input: "r\n", action: menuRefresh},
+		{name: "refresh ignores case", input: "R\n", action: menuRefresh},
+		{name: "back", input: "0\n", action: menuBack},
+		{name: "closed input goes back", input: "", action: menuBack},
+		{name: "jump to clusters", input: "c\n", action: menuJump, jumpKey: "c"},
+		{name: "jump to services", input: "s\n", action: menuJump, jumpKey: "s"},
+		{name: "jump to tasks", input: "t\n", action: menuJump, jumpKey: "t"},
+		{name: "empty lines are asked again", input: "\n\nr\n", action: menuRefresh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompter(tt.input)
+			choice := p.chooseOptionWithBack("thing", options)
+			if choice.Action != tt.action || choice.JumpKey != tt.jumpKey {
+				t.Errorf("got %+v, want action %v and jump key %q", choice, tt.action, tt.jumpKey)
+			}
+		})
+	}
+}
+
+func TestChooseFromMenuDefault(t *testing.T) {
+	withPageSize(t, 0)
+	p, out := newTestPrompter("\n")
+	choice := p.chooseLabeledOptionWithBack("task", []string{"t1", "t2"}, []string{"first", "second"}, 1)
+	if choice.Action != menuPicked || choice.Value() != "t2" {
+		t.Errorf("got %+v, want the default t2", choice)
+	}
+	if !strings.Contains(out.String(), "[2]: ") {
+		t.Errorf("prompt doesn't show the default:\n%s", out)
+	}
+}
+
+func TestChooseFromMenuMultiSelect(t *testing.T) {
+	withPageSize(t, 0)
+	options := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name  string
+		input string
+		multi bool
+		want  []string
+	}{
+		{name: "several", input: "1,3\n", multi: true, want: []string{"a", "c"}},
+		{name: "spaces and duplicates", input: "2, 2 ,1\n", multi: true, want: []string{"b", "a"}},
+		{name: "out of range is asked again", input: "1,4\n2\n", multi: true, want: []string{"b"}},
+		{name: "commas without multi", input: "1,2\n3\n", multi: false, want: []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompter(tt.input)
+			choice := p.chooseLabeledOptionsWithBack("cluster", options, options, -1, tt.multi)
+			if choice.Action != menuPicked || !slices.Equal(choice.Values, tt.want) {
+				t.Errorf("got %+v, want %v picked", choice, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseFromMenuNameMatching(t *testing.T) {
+	withPageSize(t, 0)
+	options := []string{"arn:aws:ecs:us-east-1:123456789012:task/prod/abc", "arn:aws:ecs:us-east-1:123456789012:task/prod/def", "api"}
+	labels := []string{"abc (web)", "def (worker)", "api"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		warn  string
+	}{
+		{name: "option name", input: "API\n", want: options[2]},
+		{name: "label", input: "Def (Worker)\n", want: options[1]},
+		{name: "full option value", input: options[0] + "\n", want: options[0]},
+		{name: "unknown name is asked again", input: "nope\n1\n", want: options[0], warn: "Invalid choice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := newTestPrompter(tt.input)
+			choice := p.chooseLabeledOptionWithBack("task", options, labels, -1)
+			if choice.Action != menuPicked || choice.Value() != tt.want {
+				t.Errorf("got %+v, want %q picked", choice, tt.want)
+			}
+			if tt.warn != "" && !strings.Contains(out.String(), tt.warn) {
+				t.Errorf("output doesn't contain %q:\n%s", tt.warn, out)
+			}
+		})
+	}
+
+	t.Run("ambiguous name", func(t *testing.T) {
+		p, out := newTestPrompter("same\n2\n")
+		choice := p.chooseLabeledOptionWithBack("task", []string{"x", "y"}, []string{"same", "same"}, -1)
+		if choice.Value() != "y" {
+			t.Errorf("got %+v, want y picked by number", choice)
+		}
+		if !strings.Contains(out.String(), "matches 2 options") {
+			t.Errorf("no ambiguity warning:\n%s", out)
+		}
+	})
+}
+
+func TestChooseFromMenuPinnedSeparator(t *testing.T) {
+	withPageSize(t, 0)
+	p, out := newTestPrompter("3\n")
+	choice := p.chooseFromMenu("cluster", []string{"prod", "stage", "dev"}, []string{"prod", "stage", "dev"}, -1, false, 2)
+	if choice.Value() != "dev" {
+		t.Errorf("got %+v, want dev", choice)
+	}
+	lines := strings.Split(out.String(), "\n")
+	separator := slices.Index(lines, "    ----")
+	if separator < 0 || !strings.HasSuffix(lines[separator-1], " stage") {
+		t.Errorf("no separator after the pinned clusters:\n%s", out)
+	}
+}
+
+func TestPromptTimeout(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	out := &bytes.Buffer{}
+	p := newPrompter(reader, out)
+	p.timeout = 20 * time.Millisecond
+	p.exit = func(code int) {
+		panic(exitCalled{code})
+	}
+
+	code := expectExit(t, func() {
+		p.chooseOptionWithBack("cluster", []string{"a"})
+	})
+	if code != exitUserAbort {
+		t.Errorf("exit code %d, want %d", code, exitUserAbort)
+	}
+	if !strings.Contains(out.String(), "No answer after --prompt-timeout") {
+		t.Errorf("no timeout message:\n%s", out)
+	}
+}
+
+func TestPromptAnsweredBeforeTimeout(t *testing.T) {
+	p, _ := newTestPrompter("1\n")
+	p.timeout = time.Second
+	if choice := p.chooseOptionWithBack("cluster", []string{"a"}); choice.Value() != "a" {
+		t.Errorf("got %+v, want a", choice)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input      string
+		want       bool
+		defaultYes bool
+	}{
+		{input: "y\n", want: true},
+		{input: "YES\n", want: true},
+		{input: "n\n", want: false},
+		{input: "\n", want: false},
+		{input: "", want: false},
+		{input: "\n", want: true, defaultYes: true},
+		{input: "y\n", want: true, defaultYes: true},
+		{input: "no\n", want: false, defaultYes: true},
+	}
+	for _, tt := range tests {
+		p, out := newTestPrompter(tt.input)
+		got := false
+		if tt.defaultYes {
+			got = p.confirmDefaultYes("Go? (Y/n): ")
+		} else {
+			got = p.confirm("Go? (y/n): ")
+		}
+		if got != tt.want {
+			t.Errorf("input %q (default yes %t): got %t, want %t", tt.input, tt.defaultYes, got, tt.want)
+		}
+		if !strings.HasPrefix(out.String(), "Go? ") {
+			t.Errorf("question not written to the prompter's output: %q", out)
+		}
+	}
+}
+
+func TestClosedInputEndsRun(t *testing.T) {
+	tests := map[string]func(p *prompter){
+		"confirmDefaultYes": func(p *prompter) { p.confirmDefaultYes("Go? ") },
+		"waitForEnter":      func(p *prompter) { p.waitForEnter("Press Enter: ") },
+		"requireInput":      func(p *prompter) { p.requireInput("Name: ") },
+		"chooseCommand":     func(p *prompter) { p.chooseCommand("bash") },
+	}
+	for name, prompt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, _ := newTestPrompter("")
+			if code := expectExit(t, func() { prompt(p) }); code != exitUserAbort {
+				t.Errorf("exit code %d, want %d", code, exitUserAbort)
+			}
+		})
+	}
+}
+
+func TestChooseCommand(t *testing.T) {
+	tests := []struct {
+		input     string
+		preferred string
+		want      string
+	}{
+		{input: "\n", preferred: "psql", want: "psql"},
+		{input: "2\n", preferred: "psql", want: "bash"},
+		{input: "1\n", want: "sh"},
+		{input: "3\nps aux\n", want: "ps aux"},
+		{input: "4\n", want: viewLogsCommand},
+		{input: "5\n", want: detectShellCommand},
+		{input: "9\n", want: "sh"},
+	}
+	for _, tt := range tests {
+		p, _ := newTestPrompter(tt.input)
+		if got := p.chooseCommand(tt.preferred); got != tt.want {
+			t.Errorf("input %q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmLaunch(t *testing.T) {
+	sel := selection{
+		Account:    "dev (123456789012)",
+		Region:     "eu-west-1",
+		Cluster:    "prod",
+		Service:    "api",
+		Task:       "arn:aws:ecs:eu-west-1:123456789012:task/prod/0123abcd",
+		Containers: []string{"app", "sidecar"},
+	}
+	p, out := newTestPrompter("\n")
+	if !p.confirmLaunch(sel, []string{"bash", "sh"}) {
+		t.Error("Enter should confirm the launch")
+	}
+	for _, want := range []string{"Cluster:   prod", "Task:      0123abcd", "Container: app, running bash", "Container: sidecar, running sh"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	p, _ = newTestPrompter("x\n")
+	if p.confirmLaunch(sel, []string{"bash", "sh"}) {
+		t.Error("any other answer should go back")
+	}
+
+	p, out = newTestPrompter("\n")
+	p.confirmLaunch(sel, []string{detectShellCommand, detectShellCommand})
+	if !strings.Contains(out.String(), "Container: app, running the detected shell") {
+		t.Errorf("summary doesn't describe shell detection:\n%s", out)
+	}
+}
+
+func TestSearchRegion(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "filter then pick", input: "frankfurt\n1\n", want: "eu-central-1"},
+		{name: "filter by code", input: "us-gov-w\n1\n", want: "us-gov-west-1"},
+		{name: "unknown region code", input: "eu-east-7\n", want: "eu-east-7"},
+		{name: "no match is asked again", input: "atlantis\nsao paulo\n1\n", want: "sa-east-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompter(tt.input)
+			if got := p.searchRegion(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// regionInfo is a region code with its human-readable name
+type regionInfo struct {
+	Code        string
+	Description string
+}
+
+// defaultFavoriteRegions are the region quick picks when the config file has no favorite_regions
+var defaultFavoriteRegions = []string{
+	"us-east-1",
+	"us-west-2",
+	"eu-west-1",
+	"ap-southeast-1",
+	"ap-northeast-1",
+}
+
+// favoriteRegions are the region quick picks in use, set from the config file
+var favoriteRegions = defaultFavoriteRegions
+
+// knownRegions mirrors the commercial, China and GovCloud regions in the SDK's partition
+// metadata (internal/endpoints/awsrulesfn/partitions.json), which isn't importable directly.
+// It stays a static list on purpose: ec2:DescribeRegions would need credentials and a region
+// before the user has picked one. Regions added after this list can still be typed in full,
+// see regionCodePattern.
+var knownRegions = []regionInfo{
+	{"af-south-1", "Africa (Cape Town)"},
+	{"ap-east-1", "Asia Pacific (Hong Kong)"},
+	{"ap-northeast-1", "Asia Pacific (Tokyo)"},
+	{"ap-northeast-2", "Asia Pacific (Seoul)"},
+	{"ap-northeast-3", "Asia Pacific (Osaka)"},
+	{"ap-south-1", "Asia Pacific (Mumbai)"},
+	{"ap-south-2", "Asia Pacific (Hyderabad)"},
+	{"ap-southeast-1", "Asia Pacific (Singapore)"},
+	{"ap-southeast-2", "Asia Pacific (Sydney)"},
+	{"ap-southeast-3", "Asia Pacific (Jakarta)"},
+	{"ap-southeast-4", "Asia Pacific (Melbourne)"},
+	{"ca-central-1", "Canada (Central)"},
+	{"ca-west-1", "Canada West (Calgary)"},
+	{"eu-central-1", "Europe (Frankfurt)"},
+	{"eu-central-2", "Europe (Zurich)"},
+	{"eu-north-1", "Europe (Stockholm)"},
+	{"eu-south-1", "Europe (Milan)"},
+	{"eu-south-2", "Europe (Spain)"},
+	{"eu-west-1", "Europe (Ireland)"},
+	{"eu-west-2", "Europe (London)"},
+	{"eu-west-3", "Europe (Paris)"},
+	{"il-central-1", "Israel (Tel Aviv)"},
+	{"me-central-1", "Middle East (UAE)"},
+	{"me-south-1", "Middle East (Bahrain)"},
+	{"sa-east-1", "South America (Sao Paulo)"},
+	{"us-east-1", "US East (N. Virginia)"},
+	{"us-east-2", "US East (Ohio)"},
+	{"us-west-1", "US West (N. California)"},
+	{"us-west-2", "US West (Oregon)"},
+	{"cn-north-1", "China (Beijing)"},
+	{"cn-northwest-1", "China (Ningxia)"},
+	{"us-gov-east-1", "AWS GovCloud (US-East)"},
+	{"us-gov-west-1", "AWS GovCloud (US-West)"},
+}
+
+// regionCodePattern matches region codes like eu-west-1 or us-gov-east-1, so a region missing from
+// knownRegions can still be chosen by typing its code
+var regionCodePattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// filterRegions returns the known regions whose code or description contains text, ignoring case
+func filterRegions(text string) []regionInfo {
+	text = strings.ToLower(text)
+	var matches []regionInfo
+	for _, r := range knownRegions {
+		if strings.Contains(strings.ToLower(r.Code), text) || strings.Contains(strings.ToLower(r.Description), text) {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
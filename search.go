@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// searchMatch is a task found by --search, with the path that leads to it
+type searchMatch struct {
+	Cluster string
+	Service string
+	Task    string
+	Label   string
+}
+
+// searchTasks walks every cluster and service in the region and returns the tasks whose cluster,
+// service, task ID, task definition or container names contain term, ignoring case. Clusters and
+// services that can't be listed, e.g. for lack of permissions there, are skipped with a warning.
+func searchTasks(client *ecs.Client, term string) ([]searchMatch, error) {
+	term = strings.ToLower(term)
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), term)
+	}
+
+	clusterNames, err := listClusters(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []searchMatch
+	var lastErr error
+	skipped := 0
+	for _, clusterName := range clusterNames {
+		serviceNames, err := listServices(client, clusterName)
+		if err != nil {
+			spinnerPrintf("%s [%s] Skipped: %v\n", warnIcon(), clusterName, err)
+			lastErr = err
+			skipped++
+			continue
+		}
+		for _, serviceName := range serviceNames {
+			tasks, err := listServiceTasks(client, clusterName, serviceName, nil)
+			if err != nil {
+				spinnerPrintf("%s [%s/%s] Skipped: %v\n", warnIcon(), clusterName, serviceName, err)
+				continue
+			}
+			for _, task := range tasks {
+				taskArn := aws.ToString(task.TaskArn)
+				var containerNames []string
+				for _, container := range task.Containers {
+					containerNames = append(containerNames, aws.ToString(container.Name))
+				}
+
+				found := contains(clusterName) || contains(serviceName) || contains(taskArn) || contains(aws.ToString(task.TaskDefinitionArn))
+				for _, name := range containerNames {
+					found = found || contains(name)
+				}
+				if !found {
+					continue
+				}
+
+				matches = append(matches, searchMatch{
+					Cluster: clusterName,
+					Service: serviceName,
+					Task:    taskArn,
+					Label: fmt.Sprintf("%s / %s / %s (%s)", clusterName, serviceName,
+						taskArn[strings.LastIndex(taskArn, "/")+1:], strings.Join(containerNames, ", ")),
+				})
+			}
+		}
+	}
+	// Nothing could be searched, so the error says more than an empty result would
+	if skipped > 0 && skipped == len(clusterNames) {
+		return nil, lastErr
+	}
+	return matches, nil
+}
+
+// chooseSearchMatch runs --search and lets the user pick one of the tasks found. It fills in the
+// selection and returns the step to continue with, or stepCluster to browse normally instead.
+func chooseSearchMatch(client *ecs.Client, sel *selection, term string) sessionStep {
+	// Refreshing runs the search again, tasks may have started or stopped since
+	for {
+		matches, err := withSpinner(fmt.Sprintf("Searching all clusters for %q...", term), func() ([]searchMatch, error) {
+			return searchTasks(client, term)
+		})
+		if err != nil {
+			fatal(awsExitCode(err), "%s Unable to search tasks: %v", errorIcon(), err)
+		}
+		if len(matches) == 0 {
+			fatal(exitNoResources, "%s No task in region %s matches %q", errorIcon(), sel.Region, term)
+		}
+
+		options := make([]string, len(matches))
+		labels := make([]string, len(matches))
+		for i, match := range matches {
+			options[i] = match.Task
+			labels[i] = match.Label
+		}
+
+		choice := chooseLabeledOptionWithBack("task matching "+term, options, labels, -1)
+		if choice.Action == menuRefresh {
+			continue
+		}
+		// Going back, or jumping, leaves the search for the normal menus
+		if choice.Action != menuPicked {
+			return stepCluster
+		}
+		for _, match := range matches {
+			if match.Task == choice.Value() {
+				sel.Cluster = match.Cluster
+				sel.Service = match.Service
+				sel.Task = match.Task
+				return stepContainer
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// detectShellCommand is returned by chooseCommand when the shell should be detected automatically
+const detectShellCommand = "DETECT"
+
+// shellProbe prints the path of the first shell found in the container
+const shellProbe = "sh -c 'command -v bash || command -v ash || command -v sh'"
+
+// detectShell probes the container for the best available shell, falling back to sh
+func detectShell(region string, clusterArn string, taskArn string, containerName string) string {
+	statusf("%s Detecting the shell available in %s...\n", searchIcon(), containerName)
+
+	output, err := runNonInteractive(region, clusterArn, taskArn, containerName, shellProbe)
+	if err != nil {
+		fmt.Printf("%s Shell detection failed, falling back to sh: %v\n", warnIcon(), err)
+		return "sh"
+	}
+
+	// The output also contains the Session Manager banner, so look for a shell path
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "/") {
+			continue
+		}
+		switch shell := path.Base(line); shell {
+		case "bash", "ash", "sh":
+			statusf("%s Using %s\n", okIcon(), shell)
+			return shell
+		}
+	}
+
+	fmt.Printf("%s No shell detected, falling back to sh\n", warnIcon())
+	return "sh"
+}
+
+// runNonInteractive runs a command in the container without attaching the terminal and returns
+// its output. ECS only supports interactive exec sessions, so this still passes --interactive
+// but leaves stdin unconnected so the session ends when the command does.
+func runNonInteractive(region string, clusterArn string, taskArn string, containerName string, command string) ([]byte, error) {
+	if nativeSession {
+		cmd, err := nativeSessionCommand(context.Background(), region, clusterArn, taskArn, containerName, command)
+		if err != nil {
+			return nil, err
+		}
+		return cmd.CombinedOutput()
+	}
+	cmd := exec.CommandContext(context.Background(), "aws", execCommandArgs(region, clusterArn, taskArn, containerName, command)...)
+	cmd.Env = childEnv()
+	runInOwnProcessGroup(cmd, false)
+	return cmd.CombinedOutput()
+}
+
+// allContainers is the container menu option for running the command in every container. It can't
+// be a container name, which only has letters, numbers, hyphens and underscores.
+const allContainers = "*"
+
+// isShellCommand reports whether the command just starts a shell, which needs a terminal
+func isShellCommand(command string) bool {
+	switch path.Base(strings.TrimSpace(command)) {
+	case "sh", "bash", "ash", "zsh", "dash":
+		return true
+	}
+	return false
+}
+
+// runInAllContainers runs the command in each of the selected containers one after another, without
+// a terminal, printing each line of output labelled with its container
+func runInAllContainers(sel selection, command string) {
+	if err := checkDeniedCommand(command); err != nil {
+		fatal(exitConfigError, "%s Refusing to start the session: %v", errorIcon(), err)
+	}
+
+	var failed []string
+	for _, container := range sel.Containers {
+		if dryRun {
+			fmt.Println(shellJoin(append([]string{"aws"}, execCommandArgs(sel.Region, sel.Cluster, sel.Task, container, command)...)))
+			continue
+		}
+		statusf("%s Running %q in %s...\n", launchIcon(), command, container)
+		output, err := runNonInteractive(sel.Region, sel.Cluster, sel.Task, container, command)
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			fmt.Printf("[%s] %s\n", container, scanner.Text())
+		}
+		if err != nil {
+			log.Printf("%s [%s] %v", errorIcon(), container, err)
+			failed = append(failed, container)
+		}
+	}
+	if len(failed) > 0 {
+		log.Printf("%s The command failed in: %s", errorIcon(), strings.Join(failed, ", "))
+	}
+}
+
+// readCommandFile turns a local script into a single execute-command command. One-line scripts are
+// sent as they are; longer ones are base64-encoded, since --command can't carry newlines reliably,
+// and decoded and run by sh on the container side.
+func readCommandFile(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read --command-from-file: %v", err)
+	}
+	script := strings.TrimSpace(string(data))
+	if script == "" {
+		return "", fmt.Errorf("--command-from-file %s is empty", filePath)
+	}
+	if !strings.Contains(script, "\n") {
+		return script, nil
+	}
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(script + "\n"))
+	return fmt.Sprintf("sh -c 'echo %s | base64 -d | sh'", encoded), nil
+}
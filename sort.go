@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// menuSort is the --sort order of the service and task menus. Empty keeps the default of
+// services by name and tasks oldest first.
+var menuSort string
+
+// validSortOrders lists the values --sort accepts
+var validSortOrders = map[string]bool{"": true, "name": true, "age": true, "status": true}
+
+// taskStatusOrder ranks a task's last status so tasks that can be exec'd into come first
+var taskStatusOrder = map[string]int{
+	"RUNNING":        0,
+	"ACTIVATING":     1,
+	"PENDING":        2,
+	"PROVISIONING":   3,
+	"DEACTIVATING":   4,
+	"STOPPING":       5,
+	"DEPROVISIONING": 6,
+	"STOPPED":        7,
+}
+
+// sortTasksForMenu orders tasks by --sort: name sorts by task ID, age puts the newest first and
+// status puts running tasks first. Without --sort the tasks stay in the order sortTasks gave them.
+func sortTasksForMenu(tasks []types.Task) {
+	switch menuSort {
+	case "name":
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return aws.ToString(tasks[i].TaskArn) < aws.ToString(tasks[j].TaskArn)
+		})
+	case "age":
+		// sortTasks already put them oldest first, with tasks that haven't started last
+		sortTasks(tasks)
+		started := 0
+		for started < len(tasks) && tasks[started].StartedAt != nil {
+			started++
+		}
+		for i, j := 0, started-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	case "status":
+		sort.SliceStable(tasks, func(i, j int) bool {
+			return statusRank(tasks[i]) < statusRank(tasks[j])
+		})
+	}
+}
+
+// statusRank returns the position of the task's last status in taskStatusOrder, unknown statuses last
+func statusRank(task types.Task) int {
+	if rank, ok := taskStatusOrder[aws.ToString(task.LastStatus)]; ok {
+		return rank
+	}
+	return len(taskStatusOrder)
+}
+
+// sortServicesForMenu orders the service names by --sort: age puts the newest services first and
+// status puts services running fewer tasks than desired first. Both need DescribeServices, so
+// name and the default order leave the names as listed.
+func sortServicesForMenu(client *ecs.Client, clusterArn string, names []string) ([]string, error) {
+	if menuSort != "age" && menuSort != "status" {
+		return names, nil
+	}
+
+	// DescribeServices takes at most 10 services per call
+	const batchSize = 10
+	var services []types.Service
+	for start := 0; start < len(names); start += batchSize {
+		end := min(start+batchSize, len(names))
+		output, err := client.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
+			Cluster:  &clusterArn,
+			Services: names[start:end],
+		})
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:DescribeServices", "services in cluster "+clusterArn)
+		}
+		services = append(services, output.Services...)
+	}
+
+	sort.SliceStable(services, func(i, j int) bool {
+		a, b := services[i], services[j]
+		if menuSort == "status" {
+			aDegraded, bDegraded := a.RunningCount < a.DesiredCount, b.RunningCount < b.DesiredCount
+			if aDegraded != bDegraded {
+				return aDegraded
+			}
+			return aws.ToString(a.ServiceName) < aws.ToString(b.ServiceName)
+		}
+		if a.CreatedAt == nil || b.CreatedAt == nil {
+			return a.CreatedAt != nil
+		}
+		return a.CreatedAt.After(*b.CreatedAt)
+	})
+
+	sorted := make([]string, len(services))
+	for i, service := range services {
+		sorted[i] = aws.ToString(service.ServiceName)
+	}
+	return sorted, nil
+}
+
+// pinnedClusters are the --cluster-order or pinned_clusters names shown at the top of the cluster menu
+var pinnedClusters []string
+
+// pinNames moves the pinned names that exist to the front, in the pinned order, and returns how
+// many it moved. The other names keep their order.
+func pinNames(names []string, pinned []string) ([]string, int) {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+
+	ordered := make([]string, 0, len(names))
+	moved := make(map[string]bool, len(pinned))
+	for _, name := range pinned {
+		if present[name] && !moved[name] {
+			ordered = append(ordered, name)
+			moved[name] = true
+		}
+	}
+	for _, name := range names {
+		if !moved[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered, len(moved)
+}
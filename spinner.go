@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerMu keeps the spinner from drawing over lines printed while it runs
+var spinnerMu sync.Mutex
+
+// withSpinner runs fn while showing a spinner and message on stderr. Nothing is drawn when
+// stderr isn't a terminal, so piped output stays clean.
+func withSpinner[T any](message string, fn func() (T, error)) (T, error) {
+	if quiet || !isTerminal(os.Stderr) {
+		return fn()
+	}
+
+	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	if noEmoji {
+		frames = []string{"|", "/", "-", "\\"}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			spinnerMu.Lock()
+			fmt.Fprintf(os.Stderr, "\r%s %s", frames[i%len(frames)], message)
+			spinnerMu.Unlock()
+			select {
+			case <-done:
+				// Erase the spinner line
+				spinnerMu.Lock()
+				fmt.Fprint(os.Stderr, "\r\033[K")
+				spinnerMu.Unlock()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	result, err := fn()
+	close(done)
+	<-stopped
+	return result, err
+}
+
+// spinnerPrintf prints a line to stderr while a spinner may be running. The spinner line is
+// cleared first and drawn again below on its next frame.
+func spinnerPrintf(format string, args ...any) {
+	spinnerMu.Lock()
+	defer spinnerMu.Unlock()
+	if isTerminal(os.Stderr) {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// stoppedTasks lists the service's stopped tasks and why they stopped instead of its running tasks
+var stoppedTasks bool
+
+// listStoppedTasks returns the described stopped tasks of a service, most recently stopped first.
+// ECS only keeps stopped tasks for a short while, so these are the recent ones.
+func listStoppedTasks(client *ecs.Client, clusterArn string, serviceName string) ([]types.Task, error) {
+	input := serviceTasksInput(clusterArn, serviceName)
+	input.DesiredStatus = types.DesiredStatusStopped
+	var taskArns []string
+	paginator := ecs.NewListTasksPaginator(client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:ListTasks", "cluster "+clusterArn)
+		}
+		taskArns = append(taskArns, output.TaskArns...)
+	}
+
+	tasks, err := describeTasks(client, clusterArn, taskArns)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i].StoppedAt, tasks[j].StoppedAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	return tasks, nil
+}
+
+// stoppedTaskLabel shows when the task stopped and the start of its stop reason
+func stoppedTaskLabel(task types.Task) string {
+	taskArn := aws.ToString(task.TaskArn)
+	label := taskArn[strings.LastIndex(taskArn, "/")+1:]
+	if task.StoppedAt != nil {
+		label += fmt.Sprintf(" (stopped %s ago)", time.Since(*task.StoppedAt).Round(time.Second))
+	}
+	if reason := aws.ToString(task.StoppedReason); reason != "" {
+		if len(reason) > 60 {
+			reason = reason[:57] + "..."
+		}
+		label += ": " + reason
+	}
+	return label
+}
+
+// printStoppedTask shows why the task stopped and how each of its containers exited
+func printStoppedTask(task types.Task) {
+	fmt.Printf("%s Task %s\n", searchIcon(), aws.ToString(task.TaskArn))
+	fmt.Printf("   Task definition: %s\n", aws.ToString(task.TaskDefinitionArn))
+	if task.StartedAt != nil {
+		fmt.Printf("   Started:         %s\n", task.StartedAt.Local().Format(time.DateTime))
+	}
+	if task.StoppedAt != nil {
+		fmt.Printf("   Stopped:         %s\n", task.StoppedAt.Local().Format(time.DateTime))
+	}
+	if task.StopCode != "" {
+		fmt.Printf("   Stop code:       %s\n", task.StopCode)
+	}
+	fmt.Printf("   Stopped reason:  %s\n", aws.ToString(task.StoppedReason))
+
+	fmt.Println("   Containers:")
+	for _, container := range task.Containers {
+		exitCode := "-"
+		if container.ExitCode != nil {
+			exitCode = fmt.Sprint(*container.ExitCode)
+		}
+		fmt.Printf("   - %s: %s, exit code %s\n", aws.ToString(container.Name), aws.ToString(container.LastStatus), exitCode)
+		if reason := aws.ToString(container.Reason); reason != "" {
+			fmt.Printf("     %s\n", reason)
+		}
+	}
+}
+
+// browseStoppedTasks is the task menu in --stopped mode. Picking a task shows why it stopped and
+// comes back to the list; there is no way to connect to a stopped task. It returns the next step.
+func browseStoppedTasks(client *ecs.Client, sel *selection, step sessionStep) sessionStep {
+	for {
+		tasks, err := withSpinner("Loading stopped tasks...", func() ([]types.Task, error) {
+			return listStoppedTasks(client, sel.Cluster, sel.Service)
+		})
+		if err != nil {
+			fatal(awsExitCode(err), "%s Unable to list stopped tasks: %v", errorIcon(), err)
+		}
+		if len(tasks) == 0 {
+			fmt.Printf("%s Service %s has no recently stopped tasks, ECS keeps them for about an hour\n", infoIcon(), sel.Service)
+			waitForEnter(fmt.Sprintf("%s Press Enter to go back to the services: ", promptIcon()))
+			return stepService
+		}
+
+		taskArns := make([]string, len(tasks))
+		labels := make([]string, len(tasks))
+		for i, task := range tasks {
+			taskArns[i] = aws.ToString(task.TaskArn)
+			labels[i] = stoppedTaskLabel(task)
+		}
+
+		choice := chooseLabeledOptionWithBack("stopped task", taskArns, labels, -1)
+		switch choice.Action {
+		case menuRefresh:
+			continue
+		case menuBack:
+			return stepService
+		case menuJump:
+			target, _ := jumpTarget(choice, step)
+			return target
+		}
+
+		clearScreen()
+		for _, task := range tasks {
+			if aws.ToString(task.TaskArn) == choice.Value() {
+				printStoppedTask(task)
+			}
+		}
+		waitForEnter(fmt.Sprintf("%s Press Enter to go back to the stopped tasks: ", promptIcon()))
+		clearScreen()
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// tagFlags are the --tag KEY=VALUE filters, a cluster or service has to carry all of them
+var tagFlags []string
+
+// requiredTags holds the parsed --tag filters, empty when none were given
+var requiredTags map[string]string
+
+// parseTagFilters turns KEY=VALUE pairs into a map, rejecting pairs without a key and the same
+// key with two values, which no resource could carry
+func parseTagFilters(values []string) (map[string]string, error) {
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --tag %q: must be KEY=VALUE", value)
+		}
+		if previous, ok := tags[key]; ok && previous != tagValue {
+			return nil, fmt.Errorf("invalid --tag %q: %s is already required to be %q", value, key, previous)
+		}
+		tags[key] = tagValue
+	}
+	return tags, nil
+}
+
+// hasTags reports whether the resource's tags include every required tag
+func hasTags(tags []types.Tag, required map[string]string) bool {
+	found := 0
+	for _, tag := range tags {
+		if value, ok := required[aws.ToString(tag.Key)]; ok && value == aws.ToString(tag.Value) {
+			found++
+		}
+	}
+	return found == len(required)
+}
+
+// filterClustersByTags keeps the clusters carrying all the --tag filters, in the order given
+func filterClustersByTags(client *ecs.Client, names []string) ([]string, error) {
+	if len(requiredTags) == 0 {
+		return names, nil
+	}
+
+	// DescribeClusters takes at most 100 clusters per call
+	const batchSize = 100
+	tagged := map[string]bool{}
+	for start := 0; start < len(names); start += batchSize {
+		end := min(start+batchSize, len(names))
+		output, err := client.DescribeClusters(context.TODO(), &ecs.DescribeClustersInput{
+			Clusters: names[start:end],
+			Include:  []types.ClusterField{types.ClusterFieldTags},
+		})
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:DescribeClusters", "all clusters")
+		}
+		for _, cluster := range output.Clusters {
+			if hasTags(cluster.Tags, requiredTags) {
+				tagged[aws.ToString(cluster.ClusterName)] = true
+			}
+		}
+	}
+
+	// DescribeClusters answers in its own order, so keep the sorted and pinned order of names
+	var filtered []string
+	for _, name := range names {
+		if tagged[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// filterServicesByTags keeps the services carrying all the --tag filters, in the order given
+func filterServicesByTags(client *ecs.Client, clusterArn string, names []string) ([]string, error) {
+	if len(requiredTags) == 0 {
+		return names, nil
+	}
+
+	// DescribeServices takes at most 10 services per call
+	const batchSize = 10
+	tagged := map[string]bool{}
+	for start := 0; start < len(names); start += batchSize {
+		end := min(start+batchSize, len(names))
+		output, err := client.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
+			Cluster:  &clusterArn,
+			Services: names[start:end],
+			Include:  []types.ServiceField{types.ServiceFieldTags},
+		})
+		if err != nil {
+			return nil, wrapAWSError(err, "ecs:DescribeServices", "services in cluster "+clusterArn)
+		}
+		for _, service := range output.Services {
+			if hasTags(service.Tags, requiredTags) {
+				tagged[aws.ToString(service.ServiceName)] = true
+			}
+		}
+	}
+
+	var filtered []string
+	for _, name := range names {
+		if tagged[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered, nil
+}
+
+// tagFilterNote explains an empty menu when --tag filters are in effect
+func tagFilterNote() string {
+	if len(requiredTags) == 0 {
+		return ""
+	}
+	return " with tags " + strings.Join(tagFlags, ", ")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestParseTagFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "key and value", values: []string{"team=payments"}, want: map[string]string{"team": "payments"}},
+		{name: "several", values: []string{"team=payments", "env=prod"}, want: map[string]string{"team": "payments", "env": "prod"}},
+		{name: "empty value", values: []string{"team="}, want: map[string]string{"team": ""}},
+		{name: "value with equals sign", values: []string{"query=a=b"}, want: map[string]string{"query": "a=b"}},
+		{name: "same key twice", values: []string{"env=prod", "env=prod"}, want: map[string]string{"env": "prod"}},
+		{name: "same key with two values", values: []string{"env=prod", "env=stage"}, wantErr: true},
+		{name: "missing equals sign", values: []string{"team"}, wantErr: true},
+		{name: "missing key", values: []string{"=payments"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTagFilters(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTagFilters(%q) error = %v, want error %t", tt.values, err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("parseTagFilters(%q) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasTags(t *testing.T) {
+	tags := []types.Tag{
+		{Key: aws.String("team"), Value: aws.String("payments")},
+		{Key: aws.String("env"), Value: aws.String("")},
+	}
+
+	tests := []struct {
+		name     string
+		required map[string]string
+		want     bool
+	}{
+		{name: "no filters", required: map[string]string{}, want: true},
+		{name: "one match", required: map[string]string{"team": "payments"}, want: true},
+		{name: "all match", required: map[string]string{"team": "payments", "env": ""}, want: true},
+		{name: "empty value needs an empty tag", required: map[string]string{"env": ""}, want: true},
+		{name: "wrong value", required: map[string]string{"team": "search"}, want: false},
+		{name: "missing tag", required: map[string]string{"owner": ""}, want: false},
+		{name: "one of two missing", required: map[string]string{"team": "payments", "owner": "me"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasTags(tags, tt.required); got != tt.want {
+				t.Errorf("hasTags(%v) = %t, want %t", tt.required, got, tt.want)
+			}
+		})
+	}
+}
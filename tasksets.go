@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// allTaskSets is the task set menu option for browsing the tasks of every task set
+const allTaskSets = "ALL"
+
+// chooseTaskSets asks which task set to browse when the service is deployed with task sets, as
+// CodeDeploy blue/green deployments are. It returns the task set IDs to list tasks for and the
+// step to continue with, which is stepTask unless the user went back or jumped to another menu.
+func chooseTaskSets(service *types.Service) (taskSetIDs []string, next sessionStep) {
+	if len(service.TaskSets) == 0 {
+		return nil, stepTask
+	}
+
+	var allIDs []string
+	options := []string{allTaskSets}
+	labels := []string{"All task sets"}
+	for _, taskSet := range service.TaskSets {
+		id := aws.ToString(taskSet.Id)
+		allIDs = append(allIDs, id)
+		options = append(options, id)
+		// The task definition ARN ends in task-definition/<family>:<revision>
+		taskDefinition := aws.ToString(taskSet.TaskDefinition)
+		taskDefinition = taskDefinition[strings.LastIndex(taskDefinition, "/")+1:]
+		labels = append(labels, fmt.Sprintf("%s (%s, %d running, %s)", id, aws.ToString(taskSet.Status), taskSet.RunningCount, taskDefinition))
+	}
+	if len(allIDs) == 1 {
+		return allIDs, stepTask
+	}
+
+	for {
+		choice := chooseLabeledOptionWithBack("task set", options, labels, 0)
+		switch choice.Action {
+		case menuRefresh:
+			continue
+		case menuBack:
+			return nil, stepService
+		case menuJump:
+			// The task set menu sits between the services and the tasks, so jumping to the tasks
+			// needs a task set picked first
+			target, _ := jumpTarget(choice, stepTask)
+			if target == stepTask {
+				continue
+			}
+			return nil, target
+		}
+		if choice.Value() == allTaskSets {
+			return allIDs, stepTask
+		}
+		return []string{choice.Value()}, stepTask
+	}
+}
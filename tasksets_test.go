@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestChooseTaskSets(t *testing.T) {
+	withPageSize(t, 0)
+	service := &types.Service{TaskSets: []types.TaskSet{
+		{Id: aws.String("ecs-svc/blue"), Status: aws.String("PRIMARY")},
+		{Id: aws.String("ecs-svc/green"), Status: aws.String("ACTIVE")},
+	}}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		next  sessionStep
+	}{
+		{name: "all by default", input: "\n", want: []string{"ecs-svc/blue", "ecs-svc/green"}, next: stepTask},
+		{name: "one task set", input: "3\n", want: []string{"ecs-svc/green"}, next: stepTask},
+		{name: "back", input: "0\n", next: stepService},
+		{name: "jump to services", input: "s\n", next: stepService},
+		{name: "jump to clusters", input: "c\n", next: stepCluster},
+		{name: "jump to tasks asks for a task set", input: "t\n2\n", want: []string{"ecs-svc/blue"}, next: stepTask},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := stdio
+			stdio, _ = newTestPrompter(tt.input)
+			t.Cleanup(func() { stdio = previous })
+
+			taskSetIDs, next := chooseTaskSets(service)
+			if next != tt.next || !slices.Equal(taskSetIDs, tt.want) {
+				t.Errorf("got %v and step %v, want %v and step %v", taskSetIDs, next, tt.want, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// secretEnvVars are never written into the command line of a new terminal window
+var secretEnvVars = map[string]bool{
+	"AWS_ACCESS_KEY_ID":     true,
+	"AWS_SECRET_ACCESS_KEY": true,
+	"AWS_SESSION_TOKEN":     true,
+}
+
+// forwardedAWSEnv returns the AWS settings of the session environment that are safe to put on a
+// command line, warning that exported credentials stay behind and the target uses the profile instead
+func forwardedAWSEnv(target string) []string {
+	var envArgs []string
+	hasSecrets := false
+	for _, env := range childEnv() {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, "AWS_") {
+			continue
+		}
+		if secretEnvVars[name] {
+			hasSecrets = true
+			continue
+		}
+		envArgs = append(envArgs, env)
+	}
+	if hasSecrets && os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		log.Printf("%s Credentials from environment variables are not passed to the new %s, it uses your profile's credentials", warnIcon(), target)
+	}
+	return envArgs
+}
+
+// newWindowCommandLine builds the shell command run in the new window. Terminals started through
+// a launcher don't always inherit our environment, so the non-secret AWS settings are passed along.
+func newWindowCommandLine(args []string) string {
+	envArgs := forwardedAWSEnv("window")
+	command := shellJoin(append([]string{"aws"}, args...))
+	if len(envArgs) > 0 {
+		command = "env " + shellJoin(envArgs) + " " + command
+	}
+	return command
+}
+
+// openInNewWindow starts the aws CLI with args in a new terminal window. It returns an error
+// when no supported terminal launcher is available.
+func openInNewWindow(args []string) error {
+	command := newWindowCommandLine(args)
+
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf(`tell application "Terminal"
+	activate
+	do script %s
+end tell`, appleScriptString(command))
+		if os.Getenv("TERM_PROGRAM") == "iTerm.app" {
+			script = fmt.Sprintf(`tell application "iTerm"
+	activate
+	create window with default profile command %s
+end tell`, appleScriptString(command))
+		}
+		return exec.Command("osascript", "-e", script).Run()
+
+	case "linux":
+		// x-terminal-emulator is Debian's alternative for the preferred terminal
+		launchers := [][]string{
+			{"x-terminal-emulator", "-e", "sh", "-c", command},
+			{"gnome-terminal", "--", "sh", "-c", command},
+		}
+		for _, launcher := range launchers {
+			if _, err := exec.LookPath(launcher[0]); err != nil {
+				continue
+			}
+			// The launcher keeps running as long as the window is open, so don't wait for it
+			return exec.Command(launcher[0], launcher[1:]...).Start()
+		}
+		return fmt.Errorf("neither x-terminal-emulator nor gnome-terminal was found")
+	}
+	return fmt.Errorf("opening a new terminal window is not supported on %s", runtime.GOOS)
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// protectTerminal saves the terminal state before an interactive session and returns a function
+// that restores it, so a session that dies abnormally can't leave the terminal in raw mode. Like
+// the aws CLI, Ctrl+C is left to the session. SIGTERM or SIGHUP are passed on to the session's
+// process group, so the plugin doesn't outlive us, before the terminal is restored and we exit.
+func protectTerminal(cmd *exec.Cmd) func() {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return func() {}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	signal.Ignore(os.Interrupt)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			// Give the session the time to wind down the plugin before we exit
+			if err := signalProcessGroup(cmd, sig.(syscall.Signal)); err == nil {
+				select {
+				case <-done:
+				case <-time.After(sessionWaitDelay):
+				}
+			}
+			term.Restore(fd, state)
+			fmt.Fprintf(os.Stderr, "\n%s Session ended by %v\n", warnIcon(), sig)
+			exitWith(exitGeneralError)
+		case <-done:
+			close(stopped)
+		}
+	}()
+
+	return func() {
+		close(done)
+		// After a signal the goroutine above ends the run, so don't carry on with the session's error
+		<-stopped
+		signal.Stop(signals)
+		signal.Reset(os.Interrupt)
+		term.Restore(fd, state)
+	}
+}
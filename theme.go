@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// theme is the look of the UI: the markers in front of messages, the prompt arrow and the colour
+// of the menu keys. Highlight is an ANSI SGR code such as 33 (yellow), empty for no colour.
+type theme struct {
+	OK        string `yaml:"ok,omitempty"`
+	Info      string `yaml:"info,omitempty"`
+	Warn      string `yaml:"warn,omitempty"`
+	Error     string `yaml:"error,omitempty"`
+	Search    string `yaml:"search,omitempty"`
+	Prompt    string `yaml:"prompt,omitempty"`
+	Launch    string `yaml:"launch,omitempty"`
+	Highlight string `yaml:"highlight,omitempty"`
+
+	// ASCII themes also get the ASCII spinner and separators, like --no-emoji
+	ASCII bool `yaml:"-"`
+}
+
+// themes are the built-in themes --theme and the theme setting choose from
+var themes = map[string]theme{
+	"default": {OK: "✅", Info: "ℹ️ ", Warn: "⚠️ ", Error: "❌", Search: "🔍", Prompt: "➡️ ", Launch: "🚀", Highlight: "33"},
+	"plain":   {OK: "[OK]", Info: "[i]", Warn: "[!]", Error: "[ERR]", Search: "[?]", Prompt: "[>]", Launch: "[>>]", ASCII: true},
+	"minimal": {OK: "+", Info: "-", Warn: "!", Error: "x", Search: "?", Prompt: ">", Launch: ">", Highlight: "1", ASCII: true},
+}
+
+var (
+	// themeName is the --theme flag, or the theme setting of the config file
+	themeName = "default"
+	// activeTheme is the theme the UI is drawn with
+	activeTheme = themes["default"]
+)
+
+// themeNames lists the built-in themes for help and error messages
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectTheme makes the named theme active with the overrides from the config file applied.
+// --no-emoji swaps the markers of an emoji theme for the plain ones but keeps its colour.
+func selectTheme(name string, overrides theme) error {
+	selected, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q: must be one of %s", name, strings.Join(themeNames(), ", "))
+	}
+	if noEmoji && !selected.ASCII {
+		plain := themes["plain"]
+		plain.Highlight = selected.Highlight
+		selected = plain
+	}
+
+	for _, field := range []struct{ value, override *string }{
+		{&selected.OK, &overrides.OK},
+		{&selected.Info, &overrides.Info},
+		{&selected.Warn, &overrides.Warn},
+		{&selected.Error, &overrides.Error},
+		{&selected.Search, &overrides.Search},
+		{&selected.Prompt, &overrides.Prompt},
+		{&selected.Launch, &overrides.Launch},
+		{&selected.Highlight, &overrides.Highlight},
+	} {
+		if *field.override != "" {
+			*field.value = *field.override
+		}
+	}
+
+	activeTheme = selected
+	// The spinner and separators follow the theme too
+	noEmoji = noEmoji || selected.ASCII
+	return nil
+}
+
+// validThemeName reports whether name is one of the built-in themes
+func validThemeName(name string) bool {
+	_, ok := themes[name]
+	return ok
+}
+
+// yellow starts the theme's highlight colour, which is yellow in the default theme
+func yellow() string {
+	if activeTheme.Highlight == "" {
+		return ""
+	}
+	return "\033[" + activeTheme.Highlight + "m"
+}
+
+func reset() string {
+	if activeTheme.Highlight == "" {
+		return ""
+	}
+	return "\033[0m"
+}
+
+func okIcon() string {
+	return activeTheme.OK
+}
+
+func infoIcon() string {
+	return activeTheme.Info
+}
+
+func warnIcon() string {
+	return activeTheme.Warn
+}
+
+func errorIcon() string {
+	return activeTheme.Error
+}
+
+func searchIcon() string {
+	return activeTheme.Search
+}
+
+func promptIcon() string {
+	return activeTheme.Prompt
+}
+
+func launchIcon() string {
+	return activeTheme.Launch
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const (
+	// execReadyFirstPoll is the first pause of waitForExecReadyTasks, doubled after every check
+	execReadyFirstPoll = 2 * time.Second
+	// execReadyMaxPoll caps the pause between checks
+	execReadyMaxPoll = 15 * time.Second
+	// progressBarWidth is the number of cells of the wait progress bar
+	progressBarWidth = 20
+)
+
+// waitProgress is what the service looked like at the last check
+type waitProgress struct {
+	Ready   int
+	Running int32
+	Desired int32
+}
+
+// countExecReadyTasks returns how many of the service's tasks are running with execute-command enabled, out of all its tasks
+func countExecReadyTasks(client *ecs.Client, clusterArn string, serviceName string) (int, int, error) {
+	tasks, err := listServiceTasks(client, clusterArn, serviceName, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ready := 0
+	for _, task := range tasks {
+		if task.EnableExecuteCommand && aws.ToString(task.LastStatus) == "RUNNING" {
+			ready++
+		}
+	}
+	return ready, len(tasks), nil
+}
+
+// checkWaitProgress counts the exec-ready tasks and reads the service's running and desired counts
+func checkWaitProgress(client *ecs.Client, clusterArn string, serviceName string) (waitProgress, error) {
+	ready, _, err := countExecReadyTasks(client, clusterArn, serviceName)
+	if err != nil {
+		return waitProgress{}, err
+	}
+	service, err := describeService(client, clusterArn, serviceName)
+	if err != nil {
+		return waitProgress{}, err
+	}
+	return waitProgress{Ready: ready, Running: service.RunningCount, Desired: service.DesiredCount}, nil
+}
+
+// progressBar draws how much of the timeout has passed, e.g. [######--------------]
+func progressBar(elapsed time.Duration, timeout time.Duration) string {
+	filled := int(float64(progressBarWidth) * min(1, elapsed.Seconds()/timeout.Seconds()))
+	full, empty := "█", "░"
+	if noEmoji {
+		full, empty = "#", "-"
+	}
+	return "[" + strings.Repeat(full, filled) + strings.Repeat(empty, progressBarWidth-filled) + "]"
+}
+
+// drawWaitProgress shows the wait on one line that is redrawn in place. Without a terminal it
+// prints a line per check instead, so logs don't fill up with redraws.
+func drawWaitProgress(elapsed time.Duration, timeout time.Duration, progress waitProgress, checked bool) {
+	line := fmt.Sprintf("%s %s %s/%s, %d/%d running, %d ready for exec", searchIcon(), progressBar(elapsed, timeout),
+		elapsed.Round(time.Second), timeout, progress.Running, progress.Desired, progress.Ready)
+	if quiet {
+		return
+	}
+	if !isTerminal(os.Stdout) {
+		if checked {
+			fmt.Println(line)
+		}
+		return
+	}
+	fmt.Printf("\r\033[K%s", line)
+}
+
+// endWaitProgress moves past the redrawn progress line
+func endWaitProgress() {
+	if !quiet && isTerminal(os.Stdout) {
+		fmt.Println()
+	}
+}
+
+// waitForExecReadyTasks waits until the service has a running task with execute-command enabled.
+// Right after exec is enabled on a service its tasks have to be replaced before exec works, so
+// this polls, less often as time goes on, until a new task is up or the timeout passes. It
+// reports whether it had to wait.
+func waitForExecReadyTasks(client *ecs.Client, clusterArn string, service *types.Service, timeout time.Duration) (bool, error) {
+	serviceName := aws.ToString(service.ServiceName)
+	if timeout <= 0 || service.DesiredCount == 0 {
+		return false, nil
+	}
+
+	ready, _, err := countExecReadyTasks(client, clusterArn, serviceName)
+	if err != nil || ready > 0 {
+		return false, err
+	}
+
+	fmt.Printf("%s No task of %s has execute-command enabled yet, waiting up to %s for the service to replace them (Ctrl+C to stop)\n",
+		infoIcon(), serviceName, timeout)
+	progress := waitProgress{Running: service.RunningCount, Desired: service.DesiredCount}
+	started := time.Now()
+	deadline := started.Add(timeout)
+	pause := execReadyFirstPoll
+	nextCheck := started.Add(pause)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	drawWaitProgress(0, timeout, progress, true)
+	for now := range ticker.C {
+		if now.After(deadline) {
+			break
+		}
+		if now.Before(nextCheck) {
+			drawWaitProgress(now.Sub(started), timeout, progress, false)
+			continue
+		}
+
+		progress, err = checkWaitProgress(client, clusterArn, serviceName)
+		if err != nil {
+			endWaitProgress()
+			return true, err
+		}
+		drawWaitProgress(time.Since(started), timeout, progress, true)
+		if progress.Ready > 0 {
+			endWaitProgress()
+			fmt.Printf("%s %d task(s) ready for exec\n", okIcon(), progress.Ready)
+			return true, nil
+		}
+		pause = min(pause*2, execReadyMaxPoll)
+		nextCheck = time.Now().Add(pause)
+	}
+
+	endWaitProgress()
+	fmt.Printf("%s Still no task with execute-command enabled after %s, showing the current tasks\n", warnIcon(), timeout)
+	return true, nil
+}